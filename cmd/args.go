@@ -22,7 +22,7 @@ var RootCmd = &cobra.Command{
         listen,_ := cmd.Flags().GetString("listen")
         
         if url != "" {
-            models.ProcessURL(url)
+            models.ProcessSingleTarget(url)
         }
 
         if file != "" {
@@ -40,6 +40,19 @@ var RootCmd = &cobra.Command{
         proxy, _ := cmd.Flags().GetString("proxy")
         thread, _ := cmd.Flags().GetInt("thread")
         redirect, _ := cmd.Flags().GetInt("redirect")
+        assets, _ := cmd.Flags().GetBool("assets")
+        banner, _ := cmd.Flags().GetBool("banner")
+        ports, _ := cmd.Flags().GetString("ports")
+        scheme, _ := cmd.Flags().GetString("scheme")
+        fingers, _ := cmd.Flags().GetStringSlice("finger")
+        verbose, _ := cmd.Flags().GetBool("verbose")
+        include, _ := cmd.Flags().GetStringSlice("include")
+        exclude, _ := cmd.Flags().GetStringSlice("exclude")
+        minConfidence, _ := cmd.Flags().GetInt("min-confidence")
+        suppress, _ := cmd.Flags().GetStringSlice("suppress")
+        honeypotThreshold, _ := cmd.Flags().GetInt("honeypot-threshold")
+        assetsMaxCount, _ := cmd.Flags().GetInt("assets-max-count")
+        assetsMaxSize, _ := cmd.Flags().GetInt("assets-max-size")
         outputJSON, _ := cmd.Flags().GetString("output-json")
         outputXML, _ := cmd.Flags().GetString("output-xml")
         outputXLSX, _ := cmd.Flags().GetString("output-xlsx")
@@ -79,6 +92,11 @@ var RootCmd = &cobra.Command{
             os.Exit(0)
         }
 
+        // 未指定目标且标准输入为管道或重定向的文件时，从标准输入读取目标
+        if url == "" && file == "" && listen == "" && stdinIsPiped() {
+            file = "-"
+            cmd.Flags().Set("file", file)
+        }
         if url == "" && file == "" && listen == "" {
             cmd.Help()
             logger.Error("Error: Must specify one of the -u, -f, or -l parameters!")
@@ -88,13 +106,8 @@ var RootCmd = &cobra.Command{
             logger.Error("Error: You can only choose one of the -u, -f or -l parameters!")
             os.Exit(1)
         }
-        if url != "" {
-            _, err := utils.GetBaseURL(url)
-            if err != nil {
-                logger.Error("Error: %v",err)
-            }
-        }
         
+        config.ExtraFingerpaths = fingers
         if err := ensureFingerprintLibrary(); err != nil {
             logger.Error("Error: Failed to load fingerprint library from %s: %v", config.Fingerfullpath, err)
             os.Exit(1)
@@ -106,6 +119,34 @@ var RootCmd = &cobra.Command{
         }
         models.SetThread(thread)
         models.SetMaxRedirects(redirect)
+        if assets && (assetsMaxCount < 1 || assetsMaxSize < 1) {
+            logger.Error("Error: The asset count and size limits cannot be less than 1.")
+            os.Exit(1)
+        }
+        models.SetAssetOptions(assets, assetsMaxCount, assetsMaxSize)
+        models.SetBannerMode(banner)
+        if err := models.SetTargetOptions(ports, scheme, proxy != ""); err != nil {
+            logger.Error("Error: %v", err)
+            os.Exit(1)
+        }
+        if url != "" {
+            if err := models.CheckTarget(url); err != nil {
+                logger.Error("Error: Invalid target %s: %v", url, err)
+                os.Exit(1)
+            }
+        }
+        models.SetVerbose(verbose)
+        models.SetResultFilter(include, exclude)
+        if minConfidence < 0 || minConfidence > config.MaxWeight {
+            logger.Error("Error: The minimum confidence must be between 0 and %d.", config.MaxWeight)
+            os.Exit(1)
+        }
+        models.SetMinConfidence(minConfidence)
+        if err := models.SetSuppress(suppress); err != nil {
+            logger.Error("Error: %v", err)
+            os.Exit(1)
+        }
+        models.SetHoneypotThreshold(honeypotThreshold)
         if outputJSON != "" {
             err = output.SetOutput("json",outputJSON)
         }
@@ -121,8 +162,14 @@ var RootCmd = &cobra.Command{
     },
 }
 
+func stdinIsPiped() bool {
+    info, err := os.Stdin.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
 func ensureFingerprintLibrary() error {
-    if config.Isconfig {
+    // 启动时已加载官方指纹库和用户目录，指定了 --finger 时需要重新加载
+    if config.Isconfig && len(config.ExtraFingerpaths) == 0 {
         return nil
     }
 
@@ -136,8 +183,8 @@ func ensureFingerprintLibrary() error {
 
 func init() {
     PrintBanner()
-    RootCmd.Flags().StringP("url", "u", "", "Specify the recognized target,example: https://www.example.com")
-    RootCmd.Flags().StringP("file", "f", "", "Read assets from local files for fingerprint recognition, with one target per line")
+    RootCmd.Flags().StringP("url", "u", "", "Specify the recognized target, a URL, host, host:port, CIDR or IP range, example: https://www.example.com, 10.0.0.0/24")
+    RootCmd.Flags().StringP("file", "f", "", "Read targets from a file, one per line, blank lines and lines starting with # are skipped, - reads from standard input")
     RootCmd.Flags().StringP("listen", "l", "", "Using a proxy resource collector to retrieve targets, example: 127.0.0.1:6789")
     RootCmd.Flags().StringP("output-json", "j", "", "Output all results to a JSON file")
     RootCmd.Flags().StringP("output-xml", "x", "", "Output all results to a XML file")
@@ -145,6 +192,19 @@ func init() {
     RootCmd.Flags().StringP("proxy", "p", "", "Specify the proxy for accessing the target, supporting HTTP and SOCKS, example: http://127.0.0.1:8080")
     RootCmd.Flags().IntP("thread", "t", 100, "Number of fingerprint recognition threads")
     RootCmd.Flags().IntP("redirect", "r", 5, "Number of max redirects")
+    RootCmd.Flags().BoolP("assets", "", false, "Fetch same-origin JS and CSS linked from the page and match js/css rules")
+    RootCmd.Flags().IntP("assets-max-count", "", 10, "Max number of JS and CSS files to fetch per target")
+    RootCmd.Flags().IntP("assets-max-size", "", 512, "Max size in KB of a single JS or CSS file")
+    RootCmd.Flags().StringSlice("finger", nil, "Extra fingerprint file or directory layered on top of the official library and data/custom, can be repeated")
+    RootCmd.Flags().BoolP("banner", "b", false, "Treat every target as host:port and fingerprint the TCP service banner, tcp:// targets always use this mode")
+    RootCmd.Flags().StringP("ports", "", "", "Ports tried on targets without a scheme or port, example: 80,443,8000-8100")
+    RootCmd.Flags().StringP("scheme", "", "auto", "Scheme for targets without one: auto (detect what each port speaks), https, http or both")
+    RootCmd.Flags().StringSlice("include", nil, "Only output results whose category or tag is in the list, example: waf,cdn")
+    RootCmd.Flags().StringSlice("exclude", nil, "Do not output results whose category or tag is in the list, example: cdn")
+    RootCmd.Flags().IntP("min-confidence", "", 0, "Do not output results whose confidence (0-100) is lower than this value")
+    RootCmd.Flags().StringSlice("suppress", nil, "Do not output suspicious results: soft404 (results on catch-all targets not seen on the root page), soft404-host (all results of catch-all targets), honeypot (all results of likely honeypots)")
+    RootCmd.Flags().IntP("honeypot-threshold", "", 15, "Flag a target as a likely honeypot when it matches at least this many products, 0 to disable")
+    RootCmd.Flags().BoolP("verbose", "V", false, "Print the matched fingerprint ID, rules and content snippet under each result")
     RootCmd.Flags().BoolP("check-update", "c", false, "Check for updates and upgrades")
     RootCmd.Flags().BoolP("update", "", false, "Update fingerprint database")
     RootCmd.Flags().BoolP("upgrade", "", false, "Upgrade to the latest version")
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+    "os"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "hfinger/logger"
+    "hfinger/models"
+)
+
+var importCmd = &cobra.Command{
+    Use:   "import",
+    Short: "Convert fingerprints from EHole, FingerprintHub, Wappalyzer or nuclei into the hfinger format",
+    Example: `  hfinger import --format ehole -i finger.json -o ehole.json
+  hfinger import --format wappalyzer -i technologies/ -o wappalyzer.json
+  hfinger import --format nuclei -i nuclei-templates/http/technologies/tech-detect.yaml -o nuclei.json`,
+    Run: func(cmd *cobra.Command, args []string) {
+        format, _ := cmd.Flags().GetString("format")
+        input, _ := cmd.Flags().GetString("input")
+        outputPath, _ := cmd.Flags().GetString("output")
+
+        if format == "" || input == "" || outputPath == "" {
+            cmd.Help()
+            logger.Error("Error: --format, --input and --output are required!")
+            os.Exit(1)
+        }
+        if err := models.ImportFingerprints(strings.ToLower(format), input, outputPath); err != nil {
+            logger.Error("Error: %v", err)
+            os.Exit(1)
+        }
+    },
+}
+
+func init() {
+    importCmd.Flags().StringP("format", "F", "", "Source format: "+strings.Join(models.ImportFormats, ", "))
+    importCmd.Flags().StringP("input", "i", "", "Source fingerprint file, or a directory to read recursively")
+    importCmd.Flags().StringP("output", "o", "", "File to write the converted fingerprints to, entries already in the library are skipped")
+    RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+    "os"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "hfinger/config"
+    "hfinger/logger"
+    "hfinger/models"
+    "hfinger/utils"
+)
+
+var testCmd = &cobra.Command{
+    Use:   "test",
+    Short: "Run the fingerprint library against recorded fixtures and report missing or unexpected detections",
+    Long: `Run the fingerprint library offline against a directory of recorded fixtures. Each fixture is a JSON file
+holding the status, headers, body, certificate and icons of a home page response plus the expected CMS list.
+The command exits non-zero when a detection is missing or unexpected. Use --record to record a fixture from a
+live URL, its expected list is what the current library detects and can be edited by hand.`,
+    Example: `  hfinger test -d fixtures
+  hfinger test -d fixtures --record https://www.example.com`,
+    Run: func(cmd *cobra.Command, args []string) {
+        dir, _ := cmd.Flags().GetString("dir")
+        record, _ := cmd.Flags().GetStringSlice("record")
+        proxy, _ := cmd.Flags().GetString("proxy")
+        redirect, _ := cmd.Flags().GetInt("redirect")
+        fingers, _ := cmd.Flags().GetStringSlice("finger")
+
+        config.ExtraFingerpaths = fingers
+        if config.Config == nil || len(fingers) > 0 {
+            if err := config.LoadFingerprintConfig(); err != nil {
+                logger.Error("Error: Failed to load fingerprint library from %s: %v", config.Fingerfullpath, err)
+                os.Exit(1)
+            }
+        }
+
+        if len(record) > 0 {
+            if err := utils.InitializeHTTPClient(proxy, 30*time.Second, redirect); err != nil {
+                logger.Error("Error: %v", err)
+                os.Exit(1)
+            }
+            models.SetMaxRedirects(redirect)
+            failed := false
+            for _, target := range record {
+                if err := models.RecordFixture(target, dir); err != nil {
+                    logger.Error("Error: Failed to record %s: %v", target, err)
+                    failed = true
+                }
+            }
+            if failed {
+                os.Exit(1)
+            }
+            return
+        }
+
+        passed, err := models.RunFixtures(dir)
+        if err != nil {
+            logger.Error("Error: %v", err)
+            os.Exit(1)
+        }
+        if !passed {
+            os.Exit(1)
+        }
+    },
+}
+
+func init() {
+    testCmd.Flags().StringP("dir", "d", "fixtures", "Directory holding the fixture files")
+    testCmd.Flags().StringSlice("finger", nil, "Extra fingerprint file or directory layered on top of the official library, can be repeated")
+    testCmd.Flags().StringSlice("record", nil, "Record a fixture from a live URL into the fixture directory, can be repeated")
+    testCmd.Flags().StringP("proxy", "p", "", "Specify the proxy used when recording, supporting HTTP and SOCKS")
+    testCmd.Flags().IntP("redirect", "r", 5, "Number of max redirects when recording")
+    RootCmd.AddCommand(testCmd)
+}
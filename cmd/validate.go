@@ -0,0 +1,82 @@
+package cmd
+
+import (
+    "os"
+
+    "github.com/spf13/cobra"
+
+    "hfinger/config"
+    "hfinger/logger"
+)
+
+var validateCmd = &cobra.Command{
+    Use:   "validate [file...]",
+    Short: "Check fingerprint files for mistakes and exit non-zero if any are found",
+    Long: `Check fingerprint files for mistakes that make a fingerprint silently never match or match everything:
+unknown method, location or logic values, empty rule lists, favicon hashes that are not signed 32-bit integers,
+invalid regexes and match expressions, exact duplicates and keywords too short to be meaningful.
+Too short keywords are reported as warnings and fail the check unless --strict=false is given.
+Arguments can be files or directories. Without arguments the bundled finger.json and banner.json
+and the files in the user rules directory are checked.`,
+    Example: `  hfinger validate
+  hfinger validate data/finger.json custom.json
+  hfinger validate --strict=false custom.json`,
+    Run: func(cmd *cobra.Command, args []string) {
+        strict, _ := cmd.Flags().GetBool("strict")
+
+        failed := false
+        var files []string
+        for _, arg := range args {
+            expanded, err := config.FingerprintFiles(arg)
+            if err != nil {
+                logger.Error("%s: %v", arg, err)
+                failed = true
+                continue
+            }
+            files = append(files, expanded...)
+        }
+        if len(args) == 0 {
+            files = []string{config.Fingerfullpath}
+            if _, err := os.Stat(config.Bannerfullpath); err == nil {
+                files = append(files, config.Bannerfullpath)
+            }
+            local, err := config.LocalFingerprintFiles()
+            if err != nil {
+                logger.Error("Error: %v", err)
+                failed = true
+            }
+            files = append(files, local...)
+        }
+
+        for _, file := range files {
+            total, issues, err := config.ValidateFingerprintFile(file)
+            if err != nil {
+                logger.Error("%s: %v", file, err)
+                failed = true
+                continue
+            }
+            errors, warnings := 0, 0
+            for _, issue := range issues {
+                if issue.Warning {
+                    warnings++
+                    logger.Warn("%s: %s", file, issue)
+                } else {
+                    errors++
+                    logger.Error("%s: %s", file, issue)
+                }
+            }
+            logger.Hint("Checked %d fingerprints in %s, %d errors, %d warnings", total, file, errors, warnings)
+            if errors > 0 || (strict && warnings > 0) {
+                failed = true
+            }
+        }
+        if failed {
+            os.Exit(1)
+        }
+    },
+}
+
+func init() {
+    validateCmd.Flags().Bool("strict", true, "Treat warnings such as too short keywords as errors, use --strict=false to only report them")
+    RootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,144 @@
+package config
+
+// ahoCorasick 为多模式字符串匹配自动机，一次扫描即可找出文本中出现的全部模式
+type ahoCorasick struct {
+    root     [256]int32 // 根节点的完整转移表
+    nodes    []acNode
+    edges    []acEdge
+    outputs  [][]int32 // 每个节点上结束的模式 ID
+    patterns int
+}
+
+type acNode struct {
+    fail     int32
+    dict     int32 // 失败链上最近的有输出的节点，-1 表示没有
+    edgeFrom int32
+    edgeTo   int32
+}
+
+type acEdge struct {
+    ch   byte
+    next int32
+}
+
+// newAhoCorasick 使用模式列表构建自动机，模式 ID 即其在列表中的下标
+func newAhoCorasick(patterns []string) *ahoCorasick {
+    // 构建阶段使用 map 存储子节点，构建完成后压缩为有序边数组
+    children := []map[byte]int32{{}}
+    outputs := [][]int32{nil}
+    for id, pattern := range patterns {
+        state := int32(0)
+        for i := 0; i < len(pattern); i++ {
+            next, ok := children[state][pattern[i]]
+            if !ok {
+                next = int32(len(children))
+                children = append(children, map[byte]int32{})
+                outputs = append(outputs, nil)
+                children[state][pattern[i]] = next
+            }
+            state = next
+        }
+        outputs[state] = append(outputs[state], int32(id))
+    }
+
+    ac := &ahoCorasick{
+        nodes:    make([]acNode, len(children)),
+        outputs:  outputs,
+        patterns: len(patterns),
+    }
+    for i := range ac.nodes {
+        ac.nodes[i].dict = -1
+    }
+
+    // 按字节序压缩子节点
+    for state, next := range children {
+        ac.nodes[state].edgeFrom = int32(len(ac.edges))
+        for c := 0; c < 256; c++ {
+            if child, ok := next[byte(c)]; ok {
+                ac.edges = append(ac.edges, acEdge{ch: byte(c), next: child})
+            }
+        }
+        ac.nodes[state].edgeTo = int32(len(ac.edges))
+    }
+
+    // 广度优先计算失败链接
+    queue := make([]int32, 0, len(children))
+    for c := 0; c < 256; c++ {
+        if child, ok := children[0][byte(c)]; ok {
+            ac.root[c] = child
+            ac.nodes[child].fail = 0
+            queue = append(queue, child)
+        }
+    }
+    for len(queue) > 0 {
+        state := queue[0]
+        queue = queue[1:]
+        for _, edge := range ac.edges[ac.nodes[state].edgeFrom:ac.nodes[state].edgeTo] {
+            fail := ac.nodes[state].fail
+            ac.nodes[edge.next].fail = ac.step(fail, edge.ch)
+            failState := ac.nodes[edge.next].fail
+            if len(ac.outputs[failState]) > 0 {
+                ac.nodes[edge.next].dict = failState
+            } else {
+                ac.nodes[edge.next].dict = ac.nodes[failState].dict
+            }
+            queue = append(queue, edge.next)
+        }
+    }
+    return ac
+}
+
+// child 查找节点在字符 c 上的子节点，不存在时返回 -1
+func (ac *ahoCorasick) child(state int32, c byte) int32 {
+    edges := ac.edges[ac.nodes[state].edgeFrom:ac.nodes[state].edgeTo]
+    lo, hi := 0, len(edges)
+    for lo < hi {
+        mid := (lo + hi) / 2
+        switch {
+        case edges[mid].ch == c:
+            return edges[mid].next
+        case edges[mid].ch < c:
+            lo = mid + 1
+        default:
+            hi = mid
+        }
+    }
+    return -1
+}
+
+// step 计算状态转移，沿失败链回退直到找到可转移的节点
+func (ac *ahoCorasick) step(state int32, c byte) int32 {
+    for state != 0 {
+        if next := ac.child(state, c); next >= 0 {
+            return next
+        }
+        state = ac.nodes[state].fail
+    }
+    return ac.root[c]
+}
+
+// scan 扫描文本，将命中的模式在 hits 中置为 true
+func (ac *ahoCorasick) scan(text []byte, hits []bool) {
+    state := int32(0)
+    for _, c := range text {
+        state = ac.step(state, c)
+        for out := state; out > 0; out = ac.nodes[out].dict {
+            for _, id := range ac.outputs[out] {
+                hits[id] = true
+            }
+        }
+    }
+}
+
+// scanString 与 scan 相同，避免字符串到字节切片的复制
+func (ac *ahoCorasick) scanString(text string, hits []bool) {
+    state := int32(0)
+    for i := 0; i < len(text); i++ {
+        state = ac.step(state, text[i])
+        for out := state; out > 0; out = ac.nodes[out].dict {
+            for _, id := range ac.outputs[out] {
+                hits[id] = true
+            }
+        }
+    }
+}
@@ -1,32 +1,182 @@
 package config
 
 import (
+    "crypto/sha1"
+    "encoding/hex"
     "encoding/json"
+    "fmt"
     "os"
     "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
     "sync"
 )
 
 // FingerprintConfig 定义了指纹配置的结构
 type FingerprintConfig struct {
     Finger []Fingerprint `json:"finger"`
+
+    // Index 为加载时构建的关键词多模式匹配索引
+    Index *KeywordIndex `json:"-"`
+    // RootFingers 为匹配目标默认请求（首页、随机路径等）的指纹下标
+    RootFingers []int `json:"-"`
+    // Probes 为指纹声明的主动探测请求，相同请求合并为一个
+    Probes []*Probe `json:"-"`
+    // Assets 为 filehash 指纹需要下载的静态资源，按路径合并
+    Assets []*Probe `json:"-"`
+    // ScriptFingers 和 StyleFingers 为 location 为 js、css 的指纹下标，与页面引用的脚本和样式表进行匹配
+    ScriptFingers []int `json:"-"`
+    StyleFingers  []int `json:"-"`
+
+    // Banner 为 data/banner.json 中 method 为 banner 的 TCP 服务指纹
+    Banner []Fingerprint `json:"banner,omitempty"`
+    // GreetingFingers 为不发送载荷、只匹配连接后欢迎信息的 banner 指纹下标
+    GreetingFingers []int `json:"-"`
+    // BannerProbes 为 banner 指纹声明的探测载荷，相同载荷合并为一个
+    BannerProbes []*BannerProbe `json:"-"`
+
+    // Sources 为按加载顺序排列的指纹文件，第一个为官方指纹库
+    Sources []FingerprintSource `json:"-"`
+    // Products 为按 CMS 名称（忽略大小写）合并的产品信息
+    Products map[string]ProductInfo `json:"-"`
+}
+
+// Probe 为一次主动探测请求，每个目标只请求一次，响应只与声明它的指纹进行匹配
+type Probe struct {
+    Path    string
+    Method  string
+    Headers map[string]string
+    Fingers []int
+}
+
+// BannerProbe 为一次 TCP 探测，连接后发送 Payload 并读取响应，响应只与声明它的指纹进行匹配
+type BannerProbe struct {
+    Payload []byte
+    Fingers []int
 }
 
 type Fingerprint struct {
+    // ID 为指纹的唯一标识，未填写时加载时根据指纹内容生成，不随指纹在文件中的位置变化
+    ID       string   `json:"id,omitempty"`
     CMS      string   `json:"cms"`
     Method   string   `json:"method"`
     Location string   `json:"location"`
     Logic    string   `json:"logic"`
     Rule     []string `json:"rule"`
+
+    // 可选的产品信息，同一 CMS 的多条指纹只需在其中一条填写，识别结果中会带上这些信息
+    Category string   `json:"category,omitempty"` // 产品类别，如 cms、oa、waf、cdn、router
+    Tags     []string `json:"tags,omitempty"`
+    Vendor   string   `json:"vendor,omitempty"`
+    Severity string   `json:"severity,omitempty"` // 关注程度：info、low、medium、high、critical
+    // Weight 为可选的指纹权重，取值1到100，表示该指纹单独命中时的可信度，未填写时按匹配方式取默认值
+    Weight int `json:"weight,omitempty"`
+
+    // Implies 为识别出该产品时一并推导出的产品，Excludes 为识别出该产品时不再输出的产品，均为 CMS 名称
+    Implies  []string `json:"implies,omitempty"`
+    Excludes []string `json:"excludes,omitempty"`
+
+    // Path 为可选的主动探测路径，设置后该指纹只匹配对此路径的请求响应
+    Path           string            `json:"path,omitempty"`
+    RequestMethod  string            `json:"request_method,omitempty"`  // 探测请求方法，默认为 GET
+    RequestHeaders map[string]string `json:"request_headers,omitempty"` // 探测请求附加的请求头
+
+    // Payload 为 method 为 banner 时连接后发送的探测载荷，以 hex: 开头时按十六进制解码
+    Payload string `json:"payload,omitempty"`
+
+    // Hashes 为 method 为 filehash 时 path 指向的静态资源的 md5 或 sha256 摘要与版本的对应关系
+    Hashes map[string]string `json:"hashes,omitempty"`
+
+    // Not 为可选的排除规则，location 中出现任意一条时该指纹不匹配，method 为 regex 时按正则处理
+    Not []string `json:"not,omitempty"`
+
+    // Match 为可选的布尔表达式，设置后替代 method/location/logic/rule 进行匹配
+    Match string `json:"match,omitempty"`
+
+    // Version 为可选的版本提取规则，命中指纹后按顺序尝试，取第一个提取到的版本
+    Version []VersionRule `json:"version,omitempty"`
+
+    // Override 为 true 时移除之前加载的文件中同名 CMS 的全部指纹，由本文件中的指纹替代
+    Override bool `json:"override,omitempty"`
+    // Disable 为 true 时移除之前加载的文件中同名 CMS 的全部指纹，该条目本身不需要规则，也不参与匹配
+    Disable bool `json:"disable,omitempty"`
+    // Source 为指纹所在的文件
+    Source string `json:"-"`
+
+    // Regexps 为 method 为 regex 时预编译的规则，与 Rule 一一对应
+    Regexps []*regexp.Regexp `json:"-"`
+    // NotRegexps 为 method 为 regex 时预编译的排除规则，与 Not 一一对应
+    NotRegexps []*regexp.Regexp `json:"-"`
+    // HeaderRules 为 location 为 header 时解析出的规则，与 Rule 一一对应
+    HeaderRules []HeaderRule `json:"-"`
+    // NotHeaderRules 为 location 为 header 时解析出的排除规则，与 Not 一一对应
+    NotHeaderRules []HeaderRule `json:"-"`
+    // Expr 为 Match 在加载时解析出的语法树
+    Expr *Expr `json:"-"`
+}
+
+// HeaderRule 为解析后的 header 规则
+// "Server: nginx" 只匹配 Server 头的值，按指纹的 method 判断包含或正则匹配
+// "Set-Cookie~=rememberMe=deleteMe" 始终用正则匹配 Set-Cookie 头的值，与 match 表达式中的 ~= 含义相同，
+// 需要忽略大小写时在正则前加 (?i)，例如 "Set-Cookie~=(?i)rememberme"
+// 未指定头名称的规则 Name 为空，匹配所有头名称和值
+type HeaderRule struct {
+    Name    string
+    Value   string
+    Pattern *regexp.Regexp // ~= 规则预编译的正则
+}
+
+// VersionRule 定义从响应中提取版本号的规则
+type VersionRule struct {
+    Location string `json:"location"`         // 提取位置：body、header、title、cert
+    Header   string `json:"header,omitempty"` // location 为 header 时指定的头名称，留空则匹配所有头
+    Regex    string `json:"regex"`            // 包含捕获组的正则表达式
+    Group    int    `json:"group,omitempty"`  // 版本所在的捕获组，默认为第1组
+
+    Pattern *regexp.Regexp `json:"-"`
 }
 
 // Result 存储指纹识别的结果
 type Result struct {
-    URL        string
-    CMS        string
-    Server     string
-    StatusCode int
-    Title      string
+    URL         string
+    CMS         string
+    Version     string
+    Server      string
+    StatusCode  int
+    Title       string
+    IconURL     string // 命中 faviconhash 指纹的图标地址
+    IconHash    string
+    Banner      string // TCP 服务返回内容的可打印部分
+    CertSubject string // HTTPS 目标的证书信息
+    CertIssuer  string
+    CertSANs    []string
+    CertSerial  string
+    CertSHA256  string
+    GMTLS       bool // 是否通过国密（SM2）TLS 建立连接
+
+    FingerprintID string   // 命中的指纹 ID
+    MatchMethod   string   // 命中指纹的 method，match 表达式为 match
+    MatchLocation string   // 命中指纹的 location
+    MatchedRules  []string // 命中的规则
+    Evidence      string   // 命中位置附近的内容片段，faviconhash 和 filehash 为命中的哈希
+
+    Category string // 指纹库中填写的产品信息
+    Tags     []string
+    Vendor   string
+    Severity string
+
+    Confidence int // 可信度，取值0到100，由命中指纹的权重合并得出
+
+    // SoftNotFound 为 true 时目标对不存在的路径返回与首页相同的页面，且该结果未在首页识别出，可能为误报
+    SoftNotFound bool
+    // SoftNotFoundHost 为 true 时目标对不存在的路径返回与首页相同的页面，该目标的全部结果都带有此标记
+    SoftNotFoundHost bool
+    // Honeypot 为 true 时目标命中的产品数多得不合常理，可能为蜜罐
+    Honeypot bool
+
+    Implied   bool   // 为 true 时该产品由 ImpliedBy 通过 implies 推导得出，而不是直接识别
+    ImpliedBy string
 }
 
 type LastResponse struct {
@@ -48,8 +198,14 @@ var (
     FingerUrl = "https://raw.githubusercontent.com/HackAllSec/hfinger/main/data/finger.json"
     ReleaseUrl = "https://api.github.com/repos/HackAllSec/hfinger/releases/latest"
     Fingerfile = "finger.json"
+    Bannerfile = "banner.json"
+    Userdir = "custom"
     ExecutableDir = "."
     Fingerfullpath = filepath.Join(Datapath, Fingerfile)
+    Bannerfullpath = filepath.Join(Datapath, Bannerfile)
+    Userfullpath = filepath.Join(Datapath, Userdir)
+    // ExtraFingerpaths 为 --finger 指定的指纹文件或目录，在官方指纹库和用户目录之后加载
+    ExtraFingerpaths []string
     Isconfig = false
 )
 
@@ -71,27 +227,411 @@ func initRuntimePaths() {
     ExecutableDir = resolveExecutableDir()
     Datapath = filepath.Join(ExecutableDir, "data")
     Fingerfullpath = filepath.Join(Datapath, Fingerfile)
+    Bannerfullpath = filepath.Join(Datapath, Bannerfile)
+    Userfullpath = filepath.Join(Datapath, Userdir)
 }
 
 func LoadFingerprintConfig() error {
     initRuntimePaths()
 
-    data, readErr := os.ReadFile(Fingerfullpath)
-    if readErr != nil {
+    loadedConfig, err := ReadFingerprintConfig()
+    if err != nil {
         Config = nil
         Isconfig = false
-        return readErr
+        return err
     }
 
+    Config = loadedConfig
+    Isconfig = true
+    return nil
+}
+
+// ReadFingerprintConfig 读取并预编译全部指纹文件，不修改当前加载的指纹库
+func ReadFingerprintConfig() (*FingerprintConfig, error) {
+    return readFingerprintConfig(os.ReadFile)
+}
+
+// ReadValidatedFingerprintConfig 与 ReadFingerprintConfig 相同，但每个文件先按 validate 命令的规则检查
+// 检查和加载使用同一次读取的内容，只有错误会阻止加载，警告不影响
+func ReadValidatedFingerprintConfig() (*FingerprintConfig, error) {
+    return readFingerprintConfig(func(path string) ([]byte, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            return nil, err
+        }
+        _, issues, err := ValidateFingerprintData(data)
+        if err != nil {
+            return nil, err
+        }
+        for _, issue := range issues {
+            if !issue.Warning {
+                return nil, fmt.Errorf("%s", issue)
+            }
+        }
+        return data, nil
+    })
+}
+
+// readFingerprintConfig 通过 readFile 读取全部指纹文件并预编译
+func readFingerprintConfig(readFile func(string) ([]byte, error)) (*FingerprintConfig, error) {
+    loadedConfig, err := loadFingerprintSources(readFile)
+    if err != nil {
+        return nil, err
+    }
+
+    // banner 指纹库是可选的，不存在时只是无法识别 TCP 服务
+    bannerData, err := readFile(Bannerfullpath)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return loadedConfig, nil
+        }
+        return nil, fmt.Errorf("%s: %v", Bannerfullpath, err)
+    }
+    if err := ParseBannerConfig(loadedConfig, bannerData); err != nil {
+        return nil, fmt.Errorf("%s: %v", Bannerfullpath, err)
+    }
+    return loadedConfig, nil
+}
+
+// ParseFingerprintConfig 解析指纹库内容，并预编译正则、表达式和关键词索引
+func ParseFingerprintConfig(data []byte) (*FingerprintConfig, error) {
     var loadedConfig FingerprintConfig
-    if unmarshalErr := json.Unmarshal(data, &loadedConfig); unmarshalErr != nil {
-        Config = nil
-        Isconfig = false
-        return unmarshalErr
+    if err := json.Unmarshal(data, &loadedConfig); err != nil {
+        return nil, err
+    }
+    if err := compileFingerprints(&loadedConfig); err != nil {
+        return nil, err
     }
+    return &loadedConfig, nil
+}
 
-    Config = &loadedConfig
-    Isconfig = true
+// compileFingerprints 在加载指纹库时预编译正则规则，避免每次响应重复编译
+func compileFingerprints(fingerConfig *FingerprintConfig) error {
+    if err := compileFingerList(fingerConfig.Finger); err != nil {
+        return err
+    }
+    indexFingerprints(fingerConfig)
+    return nil
+}
+
+// compileFingerList 预编译一个文件中的指纹，disable 条目不参与匹配，不需要编译
+func compileFingerList(fingers []Fingerprint) error {
+    for i := range fingers {
+        fp := &fingers[i]
+        if fp.Disable {
+            continue
+        }
+        if err := compileFingerprint(fp); err != nil {
+            return fmt.Errorf("fingerprint #%d (%s): %v", i, fp.CMS, err)
+        }
+    }
+    return nil
+}
+
+// indexFingerprints 构建关键词索引，并按探测请求和匹配位置对指纹分组
+func indexFingerprints(fingerConfig *FingerprintConfig) {
+    fingerConfig.Index = buildKeywordIndex(fingerConfig.Finger)
+    fingerConfig.RootFingers, fingerConfig.Probes, fingerConfig.Assets = groupProbes(fingerConfig.Finger)
+    fingerConfig.RootFingers, fingerConfig.ScriptFingers, fingerConfig.StyleFingers = splitAssetFingers(fingerConfig.Finger, fingerConfig.RootFingers)
+    fingerConfig.Products = make(map[string]ProductInfo)
+    mergeProductInfo(fingerConfig.Products, fingerConfig.Finger)
+}
+
+// compileFingerprint 预编译单个指纹的规则、文件哈希、match 表达式和版本提取规则
+func compileFingerprint(fp *Fingerprint) error {
+    if fp.ID == "" {
+        fp.ID = fingerprintID(*fp)
+    }
+    if err := compileRules(fp); err != nil {
+        return err
+    }
+    if err := normalizeFileHashes(fp); err != nil {
+        return err
+    }
+    if fp.Match != "" {
+        // 表达式没有 location，not 无处可用，排除条件需写在表达式中
+        if len(fp.Not) > 0 {
+            return fmt.Errorf("not is not supported with match, use != or ! in the expression instead")
+        }
+        expr, err := ParseMatchExpr(fp.Match)
+        if err != nil {
+            return fmt.Errorf("invalid match expression: %v", err)
+        }
+        fp.Expr = expr
+    }
+    return compileVersionRules(fp)
+}
+
+// ValidateFingerprint 检查单个指纹能否被加载，不修改传入的指纹
+func ValidateFingerprint(fp Fingerprint) error {
+    fp.Version = append([]VersionRule(nil), fp.Version...)
+    return compileFingerprint(&fp)
+}
+
+// compileVersionRules 预编译版本提取规则，包含捕获组时 group 默认为第1组
+func compileVersionRules(fp *Fingerprint) error {
+    for j := range fp.Version {
+        vr := &fp.Version[j]
+        re, err := regexp.Compile(vr.Regex)
+        if err != nil {
+            return fmt.Errorf("invalid version regex %q: %v", vr.Regex, err)
+        }
+        if vr.Group == 0 && re.NumSubexp() > 0 {
+            vr.Group = 1
+        }
+        if vr.Group < 0 || vr.Group > re.NumSubexp() {
+            return fmt.Errorf("version regex %q has no capture group %d", vr.Regex, vr.Group)
+        }
+        vr.Pattern = re
+    }
+    return nil
+}
+
+// ParseBannerConfig 解析 banner 指纹库并合并到已加载的指纹配置中
+func ParseBannerConfig(fingerConfig *FingerprintConfig, data []byte) error {
+    var bannerConfig struct {
+        Banner []Fingerprint `json:"banner"`
+    }
+    if err := json.Unmarshal(data, &bannerConfig); err != nil {
+        return err
+    }
+    probeIndex := make(map[string]*BannerProbe)
+    var greetingFingers []int
+    var probes []*BannerProbe
+    for i := range bannerConfig.Banner {
+        fp := &bannerConfig.Banner[i]
+        if err := compileBanner(fp); err != nil {
+            return fmt.Errorf("banner fingerprint #%d (%s): %v", i, fp.CMS, err)
+        }
+        if fp.Payload == "" {
+            greetingFingers = append(greetingFingers, i)
+            continue
+        }
+        payload, _ := decodeBannerBytes(fp.Payload)
+        probe, exists := probeIndex[string(payload)]
+        if !exists {
+            probe = &BannerProbe{Payload: payload}
+            probeIndex[string(payload)] = probe
+            probes = append(probes, probe)
+        }
+        probe.Fingers = append(probe.Fingers, i)
+    }
+    fingerConfig.Banner = bannerConfig.Banner
+    if fingerConfig.Products == nil {
+        fingerConfig.Products = make(map[string]ProductInfo)
+    }
+    mergeProductInfo(fingerConfig.Products, fingerConfig.Banner)
+    fingerConfig.GreetingFingers = greetingFingers
+    fingerConfig.BannerProbes = probes
+    return nil
+}
+
+// compileBanner 校验 banner 指纹，并将以 hex: 开头的规则解码为原始字节
+func compileBanner(fp *Fingerprint) error {
+    if fp.Method != "banner" {
+        return fmt.Errorf("unsupported method %q, expected banner", fp.Method)
+    }
+    if fp.Logic != "and" && fp.Logic != "or" {
+        return fmt.Errorf("unsupported logic %q", fp.Logic)
+    }
+    if len(fp.Rule) == 0 {
+        return fmt.Errorf("banner requires at least one rule")
+    }
+    if fp.ID == "" {
+        fp.ID = fingerprintID(*fp)
+    }
+    fp.Location = "banner"
+    if _, err := decodeBannerBytes(fp.Payload); err != nil {
+        return fmt.Errorf("invalid payload: %v", err)
+    }
+    for _, rules := range [][]string{fp.Rule, fp.Not} {
+        for j, rule := range rules {
+            decoded, err := decodeBannerBytes(rule)
+            if err != nil {
+                return fmt.Errorf("invalid rule %q: %v", rule, err)
+            }
+            rules[j] = string(decoded)
+        }
+    }
+    return compileVersionRules(fp)
+}
+
+// fingerprintID 根据指纹的全部内容生成指纹 ID，内容相同的指纹 ID 相同，任一字段不同时 ID 不同
+// 加载时生成的字段和所在文件不参与计算，map 字段按键排序序列化
+func fingerprintID(fp Fingerprint) string {
+    fp.ID = ""
+    data, err := json.Marshal(fp)
+    if err != nil {
+        data = []byte(FingerprintKey(fp))
+    }
+    sum := sha1.Sum(data)
+    return "fp-" + hex.EncodeToString(sum[:4])
+}
+
+// decodeBannerBytes 解码 banner 指纹中的载荷或规则，hex: 前缀表示十六进制的二进制内容
+func decodeBannerBytes(value string) ([]byte, error) {
+    if strings.HasPrefix(value, "hex:") {
+        return hex.DecodeString(strings.ReplaceAll(value[len("hex:"):], " ", ""))
+    }
+    return []byte(value), nil
+}
+
+// splitAssetFingers 将 location 为 js、css 的指纹从默认请求的指纹中分离出来
+func splitAssetFingers(fingers []Fingerprint, rootFingers []int) ([]int, []int, []int) {
+    var pageFingers, scriptFingers, styleFingers []int
+    for _, i := range rootFingers {
+        switch fingers[i].Location {
+        case "js":
+            scriptFingers = append(scriptFingers, i)
+        case "css":
+            styleFingers = append(styleFingers, i)
+        default:
+            pageFingers = append(pageFingers, i)
+        }
+    }
+    return pageFingers, scriptFingers, styleFingers
+}
+
+// compileRules 解析 header 规则并预编译 method 为 regex 的规则和排除规则
+func compileRules(fp *Fingerprint) error {
+    var err error
+    if fp.Location == "header" {
+        if fp.HeaderRules, err = parseHeaderRules(fp.Rule); err != nil {
+            return err
+        }
+        if fp.NotHeaderRules, err = parseHeaderRules(fp.Not); err != nil {
+            return err
+        }
+    }
+    if fp.Method != "regex" {
+        return nil
+    }
+    if fp.Regexps, err = compileRegexps(fp.Rule, fp.HeaderRules); err != nil {
+        return err
+    }
+    if fp.NotRegexps, err = compileRegexps(fp.Not, fp.NotHeaderRules); err != nil {
+        return err
+    }
+    return nil
+}
+
+// parseHeaderRules 解析 "Name: value" 和 "Name~=regex" 形式的 header 规则，并预编译 ~= 规则的正则
+func parseHeaderRules(rules []string) ([]HeaderRule, error) {
+    headerRules := make([]HeaderRule, len(rules))
+    for i, rule := range rules {
+        end := 0
+        for end < len(rule) && isHeaderNameChar(rule[end]) {
+            end++
+        }
+        if end == 0 {
+            continue
+        }
+        rest := rule[end:]
+        switch {
+        case strings.HasPrefix(rest, "~="):
+            headerRules[i] = HeaderRule{Name: rule[:end], Value: strings.TrimSpace(rest[2:])}
+            re, err := regexp.Compile(headerRules[i].Value)
+            if err != nil {
+                return nil, fmt.Errorf("invalid regex %q: %v", headerRules[i].Value, err)
+            }
+            headerRules[i].Pattern = re
+        case strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, ":/"):
+            // 排除 "http://" 之类的普通关键词
+            headerRules[i] = HeaderRule{Name: rule[:end], Value: strings.TrimSpace(rest[1:])}
+        }
+    }
+    return headerRules, nil
+}
+
+func isHeaderNameChar(ch byte) bool {
+    return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_'
+}
+
+// compileRegexps 编译正则规则，指定了头名称的 header 规则只编译值部分
+func compileRegexps(rules []string, headerRules []HeaderRule) ([]*regexp.Regexp, error) {
+    regexps := make([]*regexp.Regexp, 0, len(rules))
+    for i, rule := range rules {
+        if i < len(headerRules) && headerRules[i].Name != "" {
+            rule = headerRules[i].Value
+        }
+        re, err := regexp.Compile(rule)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regex %q: %v", rule, err)
+        }
+        regexps = append(regexps, re)
+    }
+    return regexps, nil
+}
+
+// groupProbes 按路径、方法和请求头合并指纹声明的探测请求
+// method 为 filehash 的指纹按资源路径单独分组，响应体直接用于计算文件哈希
+func groupProbes(fingers []Fingerprint) ([]int, []*Probe, []*Probe) {
+    var rootFingers []int
+    var probes, assets []*Probe
+    probeIndex := make(map[string]*Probe)
+    for i := range fingers {
+        fp := &fingers[i]
+        if fp.Path == "" {
+            if fp.Method != "filehash" {
+                rootFingers = append(rootFingers, i)
+            }
+            continue
+        }
+        if !strings.HasPrefix(fp.Path, "/") {
+            fp.Path = "/" + fp.Path
+        }
+
+        if fp.Method == "filehash" {
+            key := "asset " + fp.Path
+            asset, exists := probeIndex[key]
+            if !exists {
+                asset = &Probe{Path: fp.Path, Method: "GET"}
+                probeIndex[key] = asset
+                assets = append(assets, asset)
+            }
+            asset.Fingers = append(asset.Fingers, i)
+            continue
+        }
+
+        method := strings.ToUpper(fp.RequestMethod)
+        if method == "" {
+            method = "GET"
+        }
+        headerKeys := make([]string, 0, len(fp.RequestHeaders))
+        for key, value := range fp.RequestHeaders {
+            headerKeys = append(headerKeys, key+": "+value)
+        }
+        sort.Strings(headerKeys)
+        key := method + " " + fp.Path + "\n" + strings.Join(headerKeys, "\n")
+
+        probe, exists := probeIndex[key]
+        if !exists {
+            probe = &Probe{Path: fp.Path, Method: method, Headers: fp.RequestHeaders}
+            probeIndex[key] = probe
+            probes = append(probes, probe)
+        }
+        probe.Fingers = append(probe.Fingers, i)
+    }
+    return rootFingers, probes, assets
+}
+
+// normalizeFileHashes 校验 filehash 指纹的摘要，统一转换为小写，md5 为32位、sha256 为64位十六进制
+func normalizeFileHashes(fp *Fingerprint) error {
+    if fp.Method != "filehash" {
+        return nil
+    }
+    if fp.Path == "" {
+        return fmt.Errorf("filehash requires a path")
+    }
+    hashes := make(map[string]string, len(fp.Hashes))
+    for digest, version := range fp.Hashes {
+        lower := strings.ToLower(strings.TrimSpace(digest))
+        if _, err := hex.DecodeString(lower); err != nil || (len(lower) != 32 && len(lower) != 64) {
+            return fmt.Errorf("invalid file hash %q, expected md5 or sha256 hex digest", digest)
+        }
+        hashes[lower] = version
+    }
+    fp.Hashes = hashes
     return nil
 }
 
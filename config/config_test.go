@@ -0,0 +1,174 @@
+package config
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// TestCompileRules method 为 regex 的规则、排除规则和版本规则在加载时预编译，错误信息带有指纹的序号、CMS 和出错的规则
+func TestCompileRules(t *testing.T) {
+    tests := []struct {
+        name    string
+        fp      Fingerprint
+        regexps int
+        not     int
+        err     string
+    }{
+        {"keyword is not compiled", Fingerprint{CMS: "demo", Method: "keyword", Location: "body", Rule: []string{"a(b"}, Not: []string{"[x"}}, 0, 0, ""},
+        {"regex rules and not", Fingerprint{CMS: "demo", Method: "regex", Location: "body", Rule: []string{`ver(\d+)`, "^OA$"}, Not: []string{"demo"}}, 2, 1, ""},
+        {"invalid rule", Fingerprint{CMS: "broken", Method: "regex", Location: "body", Rule: []string{"ok", "a(b"}}, 0, 0, `fingerprint #0 (broken): invalid regex "a(b"`},
+        {"invalid not", Fingerprint{CMS: "broken", Method: "regex", Location: "title", Rule: []string{"ok"}, Not: []string{"[x"}}, 0, 0, `invalid regex "[x"`},
+        {"lookahead is not supported", Fingerprint{CMS: "broken", Method: "regex", Location: "body", Rule: []string{"a(?=b)"}}, 0, 0, "invalid regex"},
+        {"invalid ~= header rule", Fingerprint{CMS: "broken", Method: "keyword", Location: "header", Rule: []string{"Server~=("}}, 0, 0, `invalid regex "("`},
+        {"header value compiled alone", Fingerprint{CMS: "demo", Method: "regex", Location: "header", Rule: []string{"Server: ^nginx$", "nginx"}}, 2, 0, ""},
+        {"invalid version regex", Fingerprint{CMS: "broken", Method: "keyword", Location: "body", Rule: []string{"ok"}, Version: []VersionRule{{Location: "body", Regex: "v(\\d"}}}, 0, 0, "invalid version regex"},
+        {"missing capture group", Fingerprint{CMS: "broken", Method: "keyword", Location: "body", Rule: []string{"ok"}, Version: []VersionRule{{Location: "body", Regex: `v(\d+)`, Group: 2}}}, 0, 0, "has no capture group 2"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fingerConfig := FingerprintConfig{Finger: []Fingerprint{tt.fp}}
+            err := compileFingerprints(&fingerConfig)
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            fp := fingerConfig.Finger[0]
+            if len(fp.Regexps) != tt.regexps || len(fp.NotRegexps) != tt.not {
+                t.Errorf("compiled %d rules and %d not rules, want %d and %d", len(fp.Regexps), len(fp.NotRegexps), tt.regexps, tt.not)
+            }
+        })
+    }
+
+    fingerConfig := FingerprintConfig{Finger: []Fingerprint{{Method: "regex", Location: "header", Rule: []string{"Server: ^nginx$", "Set-Cookie~=^rememberme="}}}}
+    if err := compileFingerprints(&fingerConfig); err != nil {
+        t.Fatal(err)
+    }
+    fp := fingerConfig.Finger[0]
+    if !fp.Regexps[0].MatchString("nginx") || fp.Regexps[0].MatchString("Server: nginx") {
+        t.Error("header rule regex is not compiled from the value only")
+    }
+    if pattern := fp.HeaderRules[1].Pattern; pattern == nil || !pattern.MatchString("rememberme=1") || pattern.MatchString("rememberMe=1") {
+        t.Error("~= header rule is not compiled as a case-sensitive regex")
+    }
+}
+
+// TestParseHeaderRules "Name: value" 只匹配指定头，"Name~=regex" 按正则匹配指定头，其余写法按普通规则匹配所有头
+func TestParseHeaderRules(t *testing.T) {
+    tests := []struct {
+        rule    string
+        name    string
+        value   string
+        pattern bool
+    }{
+        {"Server: nginx", "Server", "nginx", false},
+        {"X-Powered-By:PHP/8", "X-Powered-By", "PHP/8", false},
+        {"Set-Cookie~=rememberMe=deleteMe", "Set-Cookie", "rememberMe=deleteMe", true},
+        {"Set-Cookie~=(?i)rememberme", "Set-Cookie", "(?i)rememberme", true},
+        {"X_Custom ~= a", "", "", false},
+        {"Location: http://example.com", "Location", "http://example.com", false},
+        {"http://example.com", "", "", false},
+        {"JSESSIONID", "", "", false},
+        {": nginx", "", "", false},
+        {"", "", "", false},
+    }
+    rules := make([]string, len(tests))
+    for i, tt := range tests {
+        rules[i] = tt.rule
+    }
+    headerRules, err := parseHeaderRules(rules)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i, tt := range tests {
+        got := headerRules[i]
+        if got.Name != tt.name || got.Value != tt.value || (got.Pattern != nil) != tt.pattern {
+            t.Errorf("%q parsed as %+v, want name %q value %q pattern %v", tt.rule, got, tt.name, tt.value, tt.pattern)
+        }
+    }
+    if _, err := parseHeaderRules([]string{"Server~=("}); err == nil {
+        t.Error("expected an error for an invalid ~= regex")
+    }
+}
+
+// TestFingerprintIDDistinct 只有 not、文件哈希或版本规则不同的指纹也应得到不同的 ID
+func TestFingerprintIDDistinct(t *testing.T) {
+    base := Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app"}}
+    variants := []Fingerprint{base, base, base, base}
+    variants[1].Not = []string{"demo-test"}
+    variants[2].Version = []VersionRule{{Location: "body", Regex: `demo-app ([\d.]+)`}}
+    variants[3].Method, variants[3].Path, variants[3].Hashes = "filehash", "/app.js", map[string]string{"0123456789abcdef0123456789abcdef": "1.0"}
+
+    seen := make(map[string]int)
+    for i, fp := range variants {
+        id := fingerprintID(fp)
+        if first, exists := seen[id]; exists {
+            t.Errorf("variant #%d has the same id %s as variant #%d", i, id, first)
+        }
+        seen[id] = i
+    }
+    if fingerprintID(base) != fingerprintID(variants[0]) {
+        t.Error("id of identical fingerprints differs")
+    }
+}
+
+// TestFingerprintIDUnique 仓库指纹库中每条指纹生成的 ID 互不相同
+func TestFingerprintIDUnique(t *testing.T) {
+    data, err := os.ReadFile(filepath.Join("..", "data", "finger.json"))
+    if err != nil {
+        t.Skipf("fingerprint library not available: %v", err)
+    }
+    fingerConfig, err := ParseFingerprintConfig(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    seen := make(map[string]int)
+    for i, fp := range fingerConfig.Finger {
+        if first, exists := seen[fp.ID]; exists {
+            t.Errorf("fingerprint #%d (%s) has the same id %s as fingerprint #%d", i, fp.CMS, fp.ID, first)
+        }
+        seen[fp.ID] = i
+    }
+}
+
+// TestGroupProbes 相同路径、方法和请求头的探测只请求一次，filehash 指纹按资源路径单独分组
+func TestGroupProbes(t *testing.T) {
+    fingers := []Fingerprint{
+        {CMS: "root", Method: "keyword"},
+        {CMS: "nacos", Method: "keyword", Path: "/nacos/"},
+        {CMS: "nacos console", Method: "keyword", Path: "nacos/", RequestMethod: "get"},
+        {CMS: "nacos post", Method: "keyword", Path: "/nacos/", RequestMethod: "POST"},
+        {CMS: "nacos header", Method: "keyword", Path: "/nacos/", RequestHeaders: map[string]string{"X-Demo": "1", "Accept": "*/*"}},
+        {CMS: "nacos header order", Method: "keyword", Path: "/nacos/", RequestHeaders: map[string]string{"Accept": "*/*", "X-Demo": "1"}},
+        {CMS: "asset", Method: "filehash", Path: "/nacos/"},
+        {CMS: "asset again", Method: "filehash", Path: "nacos/"},
+        {CMS: "filehash without path", Method: "filehash"},
+    }
+    rootFingers, probes, assets := groupProbes(fingers)
+
+    if len(rootFingers) != 1 || rootFingers[0] != 0 {
+        t.Errorf("root fingers = %v, want [0]", rootFingers)
+    }
+    var got []string
+    for _, probe := range probes {
+        got = append(got, fmt.Sprintf("%s %s %d %v", probe.Method, probe.Path, len(probe.Headers), probe.Fingers))
+    }
+    want := []string{"GET /nacos/ 0 [1 2]", "POST /nacos/ 0 [3]", "GET /nacos/ 2 [4 5]"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("probes = %q, want %q", got, want)
+    }
+    if len(assets) != 1 || assets[0].Path != "/nacos/" || !reflect.DeepEqual(assets[0].Fingers, []int{6, 7}) {
+        t.Errorf("assets = %+v, want one /nacos/ asset for fingerprints 6 and 7", assets)
+    }
+    if fingers[2].Path != "/nacos/" {
+        t.Errorf("path = %q, want a leading slash", fingers[2].Path)
+    }
+}
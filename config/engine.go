@@ -0,0 +1,108 @@
+package config
+
+// KeywordIndex 在加载指纹库时将全部关键词规则按位置编译为 Aho-Corasick 自动机
+// 每个响应的每个位置只需扫描一次，再根据命中的规则 ID 计算各指纹的 and/or 逻辑
+type KeywordIndex struct {
+    automata map[string]*ahoCorasick
+    ruleIDs  [][]int32 // 指纹下标 -> 各条规则在所属位置自动机中的模式 ID，未索引的指纹为 nil
+}
+
+// KeywordHits 记录单次响应各位置命中的模式 ID
+type KeywordHits map[string][]bool
+
+// indexLocations 为可以建立索引的匹配位置
+var indexLocations = []string{"body", "header", "title"}
+
+// buildKeywordIndex 为所有可索引的关键词指纹构建自动机
+// match 表达式、正则、指定头名称的 header 规则等无法索引的指纹仍走逐条匹配
+func buildKeywordIndex(fingers []Fingerprint) *KeywordIndex {
+    idx := &KeywordIndex{
+        automata: make(map[string]*ahoCorasick),
+        ruleIDs:  make([][]int32, len(fingers)),
+    }
+    patterns := make(map[string][]string)
+    patternIDs := make(map[string]map[string]int32)
+    for _, location := range indexLocations {
+        patternIDs[location] = make(map[string]int32)
+    }
+
+    for i := range fingers {
+        fp := &fingers[i]
+        if !indexable(fp) {
+            continue
+        }
+        ids := make([]int32, len(fp.Rule))
+        for j, rule := range fp.Rule {
+            id, exists := patternIDs[fp.Location][rule]
+            if !exists {
+                id = int32(len(patterns[fp.Location]))
+                patternIDs[fp.Location][rule] = id
+                patterns[fp.Location] = append(patterns[fp.Location], rule)
+            }
+            ids[j] = id
+        }
+        idx.ruleIDs[i] = ids
+    }
+
+    for location, list := range patterns {
+        idx.automata[location] = newAhoCorasick(list)
+    }
+    return idx
+}
+
+func indexable(fp *Fingerprint) bool {
+    if fp.Expr != nil || fp.Method != "keyword" || len(fp.Rule) == 0 {
+        return false
+    }
+    if fp.Logic != "and" && fp.Logic != "or" {
+        return false
+    }
+    switch fp.Location {
+    case "body", "title":
+    case "header":
+        for _, headerRule := range fp.HeaderRules {
+            if headerRule.Name != "" {
+                return false
+            }
+        }
+    default:
+        return false
+    }
+    for _, rule := range fp.Rule {
+        if rule == "" {
+            return false
+        }
+    }
+    return true
+}
+
+// RuleIDs 返回第 i 个指纹各条规则的模式 ID，未建立索引时返回 nil
+func (idx *KeywordIndex) RuleIDs(i int) []int32 {
+    if idx == nil || i >= len(idx.ruleIDs) {
+        return nil
+    }
+    return idx.ruleIDs[i]
+}
+
+// Scan 对响应的 body、header 和 title 各扫描一次
+func (idx *KeywordIndex) Scan(body []byte, header map[string][]string, title string) KeywordHits {
+    hits := make(KeywordHits, len(idx.automata))
+    for location, ac := range idx.automata {
+        locationHits := make([]bool, ac.patterns)
+        switch location {
+        case "body":
+            ac.scan(body, locationHits)
+        case "title":
+            ac.scanString(title, locationHits)
+        case "header":
+            for key, values := range header {
+                ac.scanString(key, locationHits)
+                for _, value := range values {
+                    ac.scanString(value, locationHits)
+                }
+            }
+        }
+        hits[location] = locationHits
+    }
+    return hits
+}
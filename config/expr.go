@@ -0,0 +1,245 @@
+package config
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// Expr 为 match 表达式解析后的语法树节点
+// Op 取值为 and、or、not 或 leaf，leaf 节点的 Leaf 是一个单规则指纹，由 models 中已有的匹配器求值
+type Expr struct {
+    Op    string
+    Left  *Expr
+    Right *Expr
+    Leaf  *Fingerprint
+    Cond  string // leaf 节点的原始条件，如 body="/seeyon/"，用于输出命中的规则
+}
+
+// 表达式中允许使用的匹配位置
+var exprLocations = map[string]bool{
+    "body":        true,
+    "header":      true,
+    "title":       true,
+    "cert":        true,
+    "faviconhash": true,
+}
+
+type exprToken struct {
+    kind  string // ident、op、string、(、)、&&、||、!
+    value string
+    pos   int
+}
+
+// ParseMatchExpr 解析 match 表达式，例如：
+//   body="/seeyon/" && (header="JSESSIONID" || title="OA")
+// 支持的运算符：= 包含，== 完全相等，!= 不包含，~= 正则匹配（与 header 规则 Name~=regex 含义相同）
+func ParseMatchExpr(input string) (*Expr, error) {
+    tokens, err := tokenizeExpr(input)
+    if err != nil {
+        return nil, err
+    }
+    p := &exprParser{tokens: tokens}
+    expr, err := p.parseOr()
+    if err != nil {
+        return nil, err
+    }
+    if p.pos < len(p.tokens) {
+        tok := p.tokens[p.pos]
+        return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+    }
+    return expr, nil
+}
+
+func tokenizeExpr(input string) ([]exprToken, error) {
+    var tokens []exprToken
+    i := 0
+    for i < len(input) {
+        ch := input[i]
+        switch {
+        case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+            i++
+        case ch == '(' || ch == ')':
+            tokens = append(tokens, exprToken{kind: string(ch), value: string(ch), pos: i})
+            i++
+        case strings.HasPrefix(input[i:], "&&"), strings.HasPrefix(input[i:], "||"):
+            tokens = append(tokens, exprToken{kind: input[i : i+2], value: input[i : i+2], pos: i})
+            i += 2
+        case strings.HasPrefix(input[i:], "=="), strings.HasPrefix(input[i:], "!="), strings.HasPrefix(input[i:], "~="):
+            tokens = append(tokens, exprToken{kind: "op", value: input[i : i+2], pos: i})
+            i += 2
+        case ch == '=':
+            tokens = append(tokens, exprToken{kind: "op", value: "=", pos: i})
+            i++
+        case ch == '!':
+            tokens = append(tokens, exprToken{kind: "!", value: "!", pos: i})
+            i++
+        case ch == '"':
+            // 字符串中仅 \" 和 \\ 需要转义，其余反斜杠原样保留，方便书写正则
+            var sb strings.Builder
+            start := i
+            i++
+            closed := false
+            for i < len(input) {
+                if input[i] == '\\' && i+1 < len(input) && (input[i+1] == '"' || input[i+1] == '\\') {
+                    sb.WriteByte(input[i+1])
+                    i += 2
+                    continue
+                }
+                if input[i] == '"' {
+                    closed = true
+                    i++
+                    break
+                }
+                sb.WriteByte(input[i])
+                i++
+            }
+            if !closed {
+                return nil, fmt.Errorf("unterminated string at position %d", start)
+            }
+            tokens = append(tokens, exprToken{kind: "string", value: sb.String(), pos: start})
+        case isIdentChar(ch):
+            start := i
+            for i < len(input) && isIdentChar(input[i]) {
+                i++
+            }
+            tokens = append(tokens, exprToken{kind: "ident", value: input[start:i], pos: start})
+        default:
+            return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+        }
+    }
+    return tokens, nil
+}
+
+func isIdentChar(ch byte) bool {
+    return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_'
+}
+
+type exprParser struct {
+    tokens []exprToken
+    pos    int
+}
+
+func (p *exprParser) peek() *exprToken {
+    if p.pos < len(p.tokens) {
+        return &p.tokens[p.pos]
+    }
+    return nil
+}
+
+func (p *exprParser) parseOr() (*Expr, error) {
+    left, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+    for tok := p.peek(); tok != nil && tok.kind == "||"; tok = p.peek() {
+        p.pos++
+        right, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        left = &Expr{Op: "or", Left: left, Right: right}
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseAnd() (*Expr, error) {
+    left, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+    for tok := p.peek(); tok != nil && tok.kind == "&&"; tok = p.peek() {
+        p.pos++
+        right, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        left = &Expr{Op: "and", Left: left, Right: right}
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseUnary() (*Expr, error) {
+    tok := p.peek()
+    if tok == nil {
+        return nil, fmt.Errorf("unexpected end of expression")
+    }
+    switch tok.kind {
+    case "!":
+        p.pos++
+        inner, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return &Expr{Op: "not", Left: inner}, nil
+    case "(":
+        p.pos++
+        inner, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        closing := p.peek()
+        if closing == nil || closing.kind != ")" {
+            return nil, fmt.Errorf("missing ')' for '(' at position %d", tok.pos)
+        }
+        p.pos++
+        return inner, nil
+    case "ident":
+        return p.parseLeaf()
+    }
+    return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+}
+
+// parseLeaf 将 location op "value" 转换为单规则指纹
+func (p *exprParser) parseLeaf() (*Expr, error) {
+    if p.pos+2 >= len(p.tokens) {
+        return nil, fmt.Errorf("incomplete condition at position %d", p.tokens[p.pos].pos)
+    }
+    ident, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+    if op.kind != "op" {
+        return nil, fmt.Errorf("expected operator after %q at position %d", ident.value, op.pos)
+    }
+    if value.kind != "string" {
+        return nil, fmt.Errorf("expected quoted string after %q at position %d", op.value, value.pos)
+    }
+    location := strings.ToLower(ident.value)
+    if !exprLocations[location] {
+        return nil, fmt.Errorf("unknown location %q at position %d", ident.value, ident.pos)
+    }
+    p.pos += 3
+
+    leaf := &Fingerprint{Location: location, Logic: "and", Method: "keyword", Rule: []string{value.value}}
+    negate := false
+    if location == "faviconhash" {
+        // faviconhash 只支持相等和不相等比较
+        leaf.Method = "faviconhash"
+        switch op.value {
+        case "=", "==":
+        case "!=":
+            negate = true
+        default:
+            return nil, fmt.Errorf("operator %q is not supported for faviconhash at position %d", op.value, op.pos)
+        }
+    } else {
+        switch op.value {
+        case "=":
+        case "!=":
+            negate = true
+        case "==":
+            leaf.Method = "regex"
+            leaf.Rule = []string{"^" + regexp.QuoteMeta(value.value) + "$"}
+        case "~=":
+            leaf.Method = "regex"
+        }
+    }
+    if err := compileRules(leaf); err != nil {
+        return nil, fmt.Errorf("%v at position %d", err, value.pos)
+    }
+
+    expr := &Expr{Op: "leaf", Leaf: leaf, Cond: ident.value + op.value + strconv.Quote(value.value)}
+    if negate {
+        expr = &Expr{Op: "not", Left: expr}
+    }
+    return expr, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+    "strings"
+    "testing"
+)
+
+// exprString 将语法树还原为带括号的表达式，叶子节点输出 位置:method:规则
+func exprString(expr *Expr) string {
+    switch expr.Op {
+    case "and":
+        return "(" + exprString(expr.Left) + " && " + exprString(expr.Right) + ")"
+    case "or":
+        return "(" + exprString(expr.Left) + " || " + exprString(expr.Right) + ")"
+    case "not":
+        return "!" + exprString(expr.Left)
+    }
+    return expr.Leaf.Location + ":" + expr.Leaf.Method + ":" + strings.Join(expr.Leaf.Rule, ",")
+}
+
+// TestParseMatchExpr 运算符优先级、取反、括号以及各运算符对应的叶子节点
+func TestParseMatchExpr(t *testing.T) {
+    tests := []struct {
+        input string
+        want  string
+    }{
+        {`body="/seeyon/"`, `body:keyword:/seeyon/`},
+        {`title=="OA"`, `title:regex:^OA$`},
+        {`title=="a.b"`, `title:regex:^a\.b$`},
+        {`body!="demo"`, `!body:keyword:demo`},
+        {`body~="ver(\d+)"`, `body:regex:ver(\d+)`},
+        {`BODY="x\"y\\z"`, `body:keyword:x"y\z`},
+        {`faviconhash="-1234"`, `faviconhash:faviconhash:-1234`},
+        {`faviconhash!="-1234"`, `!faviconhash:faviconhash:-1234`},
+        {`body="a" || body="b" && title="c"`, `(body:keyword:a || (body:keyword:b && title:keyword:c))`},
+        {`(body="a" || body="b") && title="c"`, `((body:keyword:a || body:keyword:b) && title:keyword:c)`},
+        {`!body="a" && !(title="b" || cert="c")`, `(!body:keyword:a && !(title:keyword:b || cert:keyword:c))`},
+        {`body="a" && body="b" && body="c"`, `((body:keyword:a && body:keyword:b) && body:keyword:c)`},
+        {"header=\"Server: nginx\"\n\t|| header~=\"X-Powered-By: ^PHP/\"", `(header:keyword:Server: nginx || header:regex:X-Powered-By: ^PHP/)`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.input, func(t *testing.T) {
+            expr, err := ParseMatchExpr(tt.input)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := exprString(expr); got != tt.want {
+                t.Errorf("got %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestParseMatchExprErrors 语法错误和不支持的条件在加载时报告，并指出出错位置
+func TestParseMatchExprErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        err   string
+    }{
+        {``, "unexpected end of expression"},
+        {`body="a" &&`, "unexpected end of expression"},
+        {`body="a`, "unterminated string at position 5"},
+        {`body`, "incomplete condition at position 0"},
+        {`body "a" "b"`, `expected operator after "body" at position 5`},
+        {`body=a`, `expected quoted string after "=" at position 5`},
+        {`body="a" & body="b"`, `unexpected character '&' at position 9`},
+        {`body==body`, `expected quoted string after "==" at position 6`},
+        {`path="/admin"`, `unknown location "path" at position 0`},
+        {`(body="a"`, "missing ')' for '(' at position 0"},
+        {`body="a")`, `unexpected ")" at position 8`},
+        {`body="a" title="b"`, `unexpected "title" at position 9`},
+        {`faviconhash~="123"`, `operator "~=" is not supported for faviconhash at position 11`},
+        {`body~="("`, "at position 6"},
+        {`header~="Server: ("`, "at position 8"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.input, func(t *testing.T) {
+            _, err := ParseMatchExpr(tt.input)
+            if err == nil {
+                t.Fatalf("expected error containing %q", tt.err)
+            }
+            if !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("error = %q, want it to contain %q", err, tt.err)
+            }
+        })
+    }
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+    "sort"
+    "strings"
+)
+
+// Severities 为 severity 允许的取值，按关注程度从低到高排列
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// ProductInfo 为同一 CMS 的指纹中填写的产品信息
+type ProductInfo struct {
+    Category string
+    Tags     []string
+    Vendor   string
+    Severity string
+    Implies  []string
+    Excludes []string
+    Order    int // 产品在指纹库中第一次出现的顺序，互相排除时先出现的产品保留
+}
+
+// ImpliedProduct 为通过 implies 推导出的产品
+type ImpliedProduct struct {
+    CMS string
+    By  string // 推导出该产品的产品
+}
+
+// Product 返回 CMS 对应的产品信息，未填写时返回空值
+func (fingerConfig *FingerprintConfig) Product(cms string) ProductInfo {
+    return fingerConfig.Products[cmsKey(cms)]
+}
+
+// mergeProductInfo 按 CMS 名称合并指纹中的产品信息
+// category、vendor、severity 取第一条填写的指纹，tags、implies、excludes 合并去重
+func mergeProductInfo(products map[string]ProductInfo, fingers []Fingerprint) {
+    for _, fp := range fingers {
+        if fp.Category == "" && len(fp.Tags) == 0 && fp.Vendor == "" && fp.Severity == "" &&
+            len(fp.Implies) == 0 && len(fp.Excludes) == 0 {
+            continue
+        }
+        key := cmsKey(fp.CMS)
+        info, exists := products[key]
+        if !exists {
+            info.Order = len(products)
+        }
+        if info.Category == "" {
+            info.Category = strings.ToLower(strings.TrimSpace(fp.Category))
+        }
+        if info.Vendor == "" {
+            info.Vendor = strings.TrimSpace(fp.Vendor)
+        }
+        if info.Severity == "" {
+            info.Severity = strings.ToLower(strings.TrimSpace(fp.Severity))
+        }
+        for _, tag := range fp.Tags {
+            tag = strings.ToLower(strings.TrimSpace(tag))
+            if tag != "" && !containsString(info.Tags, tag) {
+                info.Tags = append(info.Tags, tag)
+            }
+        }
+        info.Implies = mergeNames(info.Implies, fp.Implies)
+        info.Excludes = mergeNames(info.Excludes, fp.Excludes)
+        products[key] = info
+    }
+}
+
+// mergeNames 合并 CMS 名称列表，忽略大小写去重
+func mergeNames(names []string, more []string) []string {
+    for _, name := range more {
+        name = strings.TrimSpace(name)
+        if name == "" {
+            continue
+        }
+        exists := false
+        for _, n := range names {
+            if cmsKey(n) == cmsKey(name) {
+                exists = true
+                break
+            }
+        }
+        if !exists {
+            names = append(names, name)
+        }
+    }
+    return names
+}
+
+// ResolveRelations 根据 implies 和 excludes 解析一次识别中直接识别出的产品
+// 返回按推导顺序排列的推导产品，以及直接识别出的产品中被其它产品 excludes 排除的产品名称
+// 先应用排除关系，被排除的产品不再推导其它产品，也不会被推导出来；推导出的产品的 excludes 同样生效，排除集合变化时重新推导
+// 直接识别出的产品按在指纹库中出现的顺序应用 excludes，已被排除的产品不再排除其它产品，互相排除时先出现的产品保留
+// 已识别出的产品不会再被推导，产品不会排除自己
+func (fingerConfig *FingerprintConfig) ResolveRelations(observed []string) ([]ImpliedProduct, []string) {
+    excludedKeys := make(map[string]bool)
+    addExcludes := func(cms string) bool {
+        changed := false
+        for _, name := range fingerConfig.Product(cms).Excludes {
+            if key := cmsKey(name); key != cmsKey(cms) && !excludedKeys[key] {
+                excludedKeys[key] = true
+                changed = true
+            }
+        }
+        return changed
+    }
+    ordered := append([]string(nil), observed...)
+    sort.SliceStable(ordered, func(i, j int) bool {
+        return fingerConfig.Product(ordered[i]).Order < fingerConfig.Product(ordered[j]).Order
+    })
+    for _, cms := range ordered {
+        if !excludedKeys[cmsKey(cms)] {
+            addExcludes(cms)
+        }
+    }
+
+    var implied []ImpliedProduct
+    for {
+        implied = fingerConfig.implyProducts(observed, excludedKeys)
+        changed := false
+        for _, product := range implied {
+            if addExcludes(product.CMS) {
+                changed = true
+            }
+        }
+        if !changed {
+            break
+        }
+    }
+
+    var excluded []string
+    for _, cms := range observed {
+        if excludedKeys[cmsKey(cms)] {
+            excluded = append(excluded, cms)
+        }
+    }
+    return implied, excluded
+}
+
+// implyProducts 从未被排除的直接识别产品出发按 implies 逐层推导，跳过被排除的产品
+func (fingerConfig *FingerprintConfig) implyProducts(observed []string, excludedKeys map[string]bool) []ImpliedProduct {
+    seen := make(map[string]bool)
+    var queue []string
+    for _, cms := range observed {
+        seen[cmsKey(cms)] = true
+        if !excludedKeys[cmsKey(cms)] {
+            queue = append(queue, cms)
+        }
+    }
+    var implied []ImpliedProduct
+    for len(queue) > 0 {
+        cms := queue[0]
+        queue = queue[1:]
+        for _, name := range fingerConfig.Product(cms).Implies {
+            if key := cmsKey(name); !seen[key] && !excludedKeys[key] {
+                seen[key] = true
+                implied = append(implied, ImpliedProduct{CMS: name, By: cms})
+                queue = append(queue, name)
+            }
+        }
+    }
+    return implied
+}
+
+// ValidSeverity 判断 severity 是否为允许的取值，未填写视为有效
+func ValidSeverity(severity string) bool {
+    severity = strings.ToLower(strings.TrimSpace(severity))
+    return severity == "" || containsString(Severities, severity)
+}
+
+func containsString(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+    "reflect"
+    "testing"
+)
+
+// relationConfig 构造只包含产品关联的指纹库
+func relationConfig(fingers ...Fingerprint) *FingerprintConfig {
+    fingerConfig := &FingerprintConfig{Products: make(map[string]ProductInfo)}
+    mergeProductInfo(fingerConfig.Products, fingers)
+    return fingerConfig
+}
+
+// TestResolveRelations 推导关联产品，被排除的产品不会再推导或排除其它产品，互相排除时保留指纹库中先出现的产品
+func TestResolveRelations(t *testing.T) {
+    fingerConfig := relationConfig(
+        Fingerprint{CMS: "Jenkins", Implies: []string{"Java"}},
+        Fingerprint{CMS: "Java", Implies: []string{"JVM"}},
+        Fingerprint{CMS: "泛微 e-cology", Implies: []string{"Java"}, Excludes: []string{"泛微 OA", "泛微 e-cology"}},
+        Fingerprint{CMS: "泛微 OA", Implies: []string{"泛微 Legacy"}},
+        Fingerprint{CMS: "Specific", Implies: []string{"Generic"}, Excludes: []string{"Umbrella"}},
+        Fingerprint{CMS: "Umbrella", Implies: []string{"Umbrella Runtime"}},
+        Fingerprint{CMS: "Generic", Excludes: []string{"Legacy Framework"}},
+        Fingerprint{CMS: "Legacy Framework", Implies: []string{"Legacy Runtime"}},
+        Fingerprint{CMS: "Beta", Excludes: []string{"Alpha"}},
+        Fingerprint{CMS: "Alpha", Implies: []string{"Alpha Runtime"}, Excludes: []string{"Beta", "Gamma"}},
+    )
+    tests := []struct {
+        name     string
+        observed []string
+        implied  []ImpliedProduct
+        excluded []string
+    }{
+        {"no relations", []string{"nginx"}, nil, nil},
+        {"transitive implies", []string{"Jenkins"}, []ImpliedProduct{{"Java", "Jenkins"}, {"JVM", "Java"}}, nil},
+        {"observed product is not implied again", []string{"Jenkins", "Java"}, []ImpliedProduct{{"JVM", "Java"}}, nil},
+        {"case-insensitive names", []string{"jenkins", "JAVA"}, []ImpliedProduct{{"JVM", "JAVA"}}, nil},
+        {"product does not exclude itself", []string{"泛微 e-cology"}, []ImpliedProduct{{"Java", "泛微 e-cology"}, {"JVM", "Java"}}, nil},
+        {"excluded product implies nothing", []string{"泛微 OA", "泛微 e-cology"},
+            []ImpliedProduct{{"Java", "泛微 e-cology"}, {"JVM", "Java"}}, []string{"泛微 OA"}},
+        {"excluded product is not implied", []string{"Specific", "Legacy Framework"},
+            []ImpliedProduct{{"Generic", "Specific"}}, []string{"Legacy Framework"}},
+        {"exclusion applies before inference", []string{"Umbrella", "Specific"},
+            []ImpliedProduct{{"Generic", "Specific"}}, []string{"Umbrella"}},
+        {"mutual exclusion keeps the product declared first", []string{"Alpha", "Beta"}, nil, []string{"Alpha"}},
+        {"mutual exclusion ignores detection order", []string{"Beta", "alpha"}, nil, []string{"alpha"}},
+        {"excluded product excludes nothing", []string{"Gamma", "Alpha", "Beta"}, nil, []string{"Alpha"}},
+        {"exclusion without the other product", []string{"Gamma", "Alpha"}, []ImpliedProduct{{"Alpha Runtime", "Alpha"}}, []string{"Gamma"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            implied, excluded := fingerConfig.ResolveRelations(tt.observed)
+            if !reflect.DeepEqual(implied, tt.implied) {
+                t.Errorf("implied = %v, want %v", implied, tt.implied)
+            }
+            if !reflect.DeepEqual(excluded, tt.excluded) {
+                t.Errorf("excluded = %v, want %v", excluded, tt.excluded)
+            }
+            for _, product := range implied {
+                for _, name := range excluded {
+                    if cmsKey(product.By) == cmsKey(name) {
+                        t.Errorf("%s implied by excluded product %s", product.CMS, name)
+                    }
+                }
+            }
+        })
+    }
+}
+
+// TestMergeProductInfo 同一产品的多条指纹合并分类、标签与关联关系，并记录产品第一次出现的顺序
+func TestMergeProductInfo(t *testing.T) {
+    fingerConfig := relationConfig(
+        Fingerprint{CMS: "Demo", Category: "OA ", Tags: []string{"Java", "oa"}},
+        Fingerprint{CMS: "demo", Category: "cms", Vendor: "Acme", Tags: []string{"java", "Login"}, Implies: []string{"Java", " "}},
+        Fingerprint{CMS: "DEMO", Implies: []string{"java", "Tomcat"}, Excludes: []string{"Demo Lite"}},
+    )
+    got := fingerConfig.Product("Demo")
+    want := ProductInfo{Category: "oa", Vendor: "Acme", Tags: []string{"java", "oa", "login"}, Implies: []string{"Java", "Tomcat"}, Excludes: []string{"Demo Lite"}}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Product(Demo) = %+v, want %+v", got, want)
+    }
+
+    fingerConfig = relationConfig(
+        Fingerprint{CMS: "Nginx"},
+        Fingerprint{CMS: "Beta", Excludes: []string{"Alpha"}},
+        Fingerprint{CMS: "Alpha", Category: "oa"},
+        Fingerprint{CMS: "beta", Category: "cms"},
+    )
+    if beta, alpha := fingerConfig.Product("Beta").Order, fingerConfig.Product("Alpha").Order; beta >= alpha {
+        t.Errorf("order of Beta = %d, Alpha = %d, want Beta first", beta, alpha)
+    }
+}
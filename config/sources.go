@@ -0,0 +1,159 @@
+package config
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// FingerprintSource 为一个指纹文件的加载统计
+type FingerprintSource struct {
+    Path       string
+    Count      int // 最终指纹库中来自该文件的指纹数
+    Overridden int // 该文件通过 override 替换的之前加载的指纹数
+    Disabled   int // 该文件通过 disable 移除的之前加载的指纹数
+}
+
+// loadFingerprintSources 加载官方指纹库，再依次叠加用户目录和 --finger 指定的指纹文件
+// 后加载的文件可以按 CMS 名称替换或禁用之前加载的指纹，官方指纹库更新时不会影响这些文件
+func loadFingerprintSources(readFile func(string) ([]byte, error)) (*FingerprintConfig, error) {
+    fingerConfig := &FingerprintConfig{}
+    fingers, err := readFingerprintFile(Fingerfullpath, readFile)
+    if err != nil {
+        return nil, err
+    }
+    var source FingerprintSource
+    fingerConfig.Finger, source = layerFingerprints(nil, fingers, Fingerfullpath)
+    fingerConfig.Sources = append(fingerConfig.Sources, source)
+
+    paths, err := LocalFingerprintFiles()
+    if err != nil {
+        return nil, err
+    }
+    for _, path := range paths {
+        fingers, err := readFingerprintFile(path, readFile)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        fingerConfig.Finger, source = layerFingerprints(fingerConfig.Finger, fingers, path)
+        fingerConfig.Sources = append(fingerConfig.Sources, source)
+    }
+
+    counts := make(map[string]int)
+    for _, fp := range fingerConfig.Finger {
+        counts[fp.Source]++
+    }
+    for i := range fingerConfig.Sources {
+        fingerConfig.Sources[i].Count = counts[fingerConfig.Sources[i].Path]
+    }
+    indexFingerprints(fingerConfig)
+    return fingerConfig, nil
+}
+
+// LocalFingerprintFiles 返回用户目录和 --finger 指定的全部指纹文件，按加载顺序排列
+// 用户目录不存在时忽略，--finger 指定的路径不存在时返回错误
+func LocalFingerprintFiles() ([]string, error) {
+    var paths []string
+    if _, err := os.Stat(Userfullpath); err == nil {
+        files, err := FingerprintFiles(Userfullpath)
+        if err != nil {
+            return nil, err
+        }
+        paths = append(paths, files...)
+    }
+    for _, extra := range ExtraFingerpaths {
+        files, err := FingerprintFiles(extra)
+        if err != nil {
+            return nil, err
+        }
+        paths = append(paths, files...)
+    }
+    return paths, nil
+}
+
+// FingerprintFiles 展开指纹文件路径，path 为目录时返回其中按路径排序的全部 .json 文件
+func FingerprintFiles(path string) ([]string, error) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return []string{path}, nil
+    }
+    var files []string
+    err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        if !d.IsDir() && strings.EqualFold(filepath.Ext(file), ".json") {
+            files = append(files, file)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    sort.Strings(files)
+    return files, nil
+}
+
+// readFingerprintFile 读取并预编译一个指纹文件，指纹的 Source 设置为文件路径
+func readFingerprintFile(path string, readFile func(string) ([]byte, error)) ([]Fingerprint, error) {
+    data, err := readFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var fingerConfig FingerprintConfig
+    if err := json.Unmarshal(data, &fingerConfig); err != nil {
+        return nil, err
+    }
+    if err := compileFingerList(fingerConfig.Finger); err != nil {
+        return nil, err
+    }
+    for i := range fingerConfig.Finger {
+        fingerConfig.Finger[i].Source = path
+    }
+    return fingerConfig.Finger, nil
+}
+
+// layerFingerprints 将一个文件的指纹叠加到已加载的指纹上
+// 文件中带有 override 或 disable 的 CMS 名称（忽略大小写）对应的已加载指纹全部移除，disable 条目本身不保留
+func layerFingerprints(loaded []Fingerprint, fingers []Fingerprint, path string) ([]Fingerprint, FingerprintSource) {
+    source := FingerprintSource{Path: path}
+    replaced := make(map[string]bool)
+    for _, fp := range fingers {
+        if fp.Override || fp.Disable {
+            name := cmsKey(fp.CMS)
+            // 同一 CMS 同时存在 override 和 disable 条目时按替换处理
+            replaced[name] = replaced[name] || !fp.Disable
+        }
+    }
+
+    merged := make([]Fingerprint, 0, len(loaded)+len(fingers))
+    for _, fp := range loaded {
+        override, exists := replaced[cmsKey(fp.CMS)]
+        if !exists {
+            merged = append(merged, fp)
+            continue
+        }
+        if override {
+            source.Overridden++
+        } else {
+            source.Disabled++
+        }
+    }
+    for _, fp := range fingers {
+        if !fp.Disable {
+            merged = append(merged, fp)
+        }
+    }
+    return merged, source
+}
+
+func cmsKey(cms string) string {
+    return strings.ToLower(strings.TrimSpace(cms))
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// setFingerprintPaths 将官方指纹库、用户目录和 --finger 指向 dir 下的文件，测试结束后恢复
+func setFingerprintPaths(t *testing.T, dir string, extra ...string) {
+    previousFinger, previousUser, previousBanner, previousExtra := Fingerfullpath, Userfullpath, Bannerfullpath, ExtraFingerpaths
+    t.Cleanup(func() {
+        Fingerfullpath, Userfullpath, Bannerfullpath, ExtraFingerpaths = previousFinger, previousUser, previousBanner, previousExtra
+    })
+    Fingerfullpath = filepath.Join(dir, "finger.json")
+    Userfullpath = filepath.Join(dir, "custom")
+    Bannerfullpath = filepath.Join(dir, "banner.json")
+    ExtraFingerpaths = extra
+}
+
+// writeFingerprintFile 将指纹文件写入 dir 下的 name，返回文件路径
+func writeFingerprintFile(t *testing.T, dir string, name string, content string) string {
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// TestLoadFingerprintSources 用户目录和 --finger 的文件依次叠加在官方指纹库上，按 CMS 名称（忽略大小写）替换或禁用之前的指纹
+func TestLoadFingerprintSources(t *testing.T) {
+    dir := t.TempDir()
+    official := writeFingerprintFile(t, dir, "finger.json", `{"finger": [
+        {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]},
+        {"cms": "Nginx", "method": "keyword", "location": "body", "logic": "or", "rule": ["Welcome to nginx"]},
+        {"cms": "Tomcat", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: Apache-Coyote"]},
+        {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa"]}
+    ]}`)
+    custom := writeFingerprintFile(t, dir, "custom/a.json", `{"finger": [
+        {"cms": "nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: openresty"], "override": true},
+        {"cms": " TOMCAT ", "disable": true},
+        {"cms": "Internal", "method": "keyword", "location": "title", "logic": "or", "rule": ["Internal Portal"]}
+    ]}`)
+    writeFingerprintFile(t, dir, "custom/notes.txt", "not a fingerprint file")
+    extra := writeFingerprintFile(t, dir, "extra.json", `{"finger": [
+        {"cms": "Internal", "disable": true},
+        {"cms": "DemoOA", "disable": true},
+        {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa-v2"], "override": true}
+    ]}`)
+    setFingerprintPaths(t, dir, extra)
+
+    fingerConfig, err := loadFingerprintSources(os.ReadFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, fp := range fingerConfig.Finger {
+        got = append(got, fp.CMS+" "+fp.Rule[0]+" "+filepath.Base(fp.Source))
+    }
+    want := []string{
+        "nginx Server: openresty a.json",
+        "DemoOA demo-oa-v2 extra.json",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("fingerprints = %q, want %q", got, want)
+    }
+    wantSources := []FingerprintSource{
+        {Path: official, Count: 0},
+        {Path: custom, Count: 1, Overridden: 2, Disabled: 1},
+        {Path: extra, Count: 1, Overridden: 1, Disabled: 1},
+    }
+    if !reflect.DeepEqual(fingerConfig.Sources, wantSources) {
+        t.Errorf("sources = %+v, want %+v", fingerConfig.Sources, wantSources)
+    }
+
+    // 加载后的指纹已预编译并建立索引
+    if len(fingerConfig.Finger[0].HeaderRules) != 1 || fingerConfig.Finger[0].ID == "" {
+        t.Errorf("layered fingerprint is not compiled: %+v", fingerConfig.Finger[0])
+    }
+}
+
+// TestLoadFingerprintSourcesErrors 用户目录不存在时忽略，--finger 的路径不存在或文件无效时报错并带有文件路径
+func TestLoadFingerprintSourcesErrors(t *testing.T) {
+    dir := t.TempDir()
+    writeFingerprintFile(t, dir, "finger.json", `{"finger": [{"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]}]}`)
+    setFingerprintPaths(t, dir)
+    fingerConfig, err := loadFingerprintSources(os.ReadFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(fingerConfig.Sources) != 1 || len(fingerConfig.Finger) != 1 {
+        t.Errorf("sources = %+v, want only the official library", fingerConfig.Sources)
+    }
+
+    setFingerprintPaths(t, dir, filepath.Join(dir, "missing.json"))
+    if _, err := loadFingerprintSources(os.ReadFile); err == nil {
+        t.Error("expected an error for a missing --finger path")
+    }
+
+    broken := writeFingerprintFile(t, dir, "broken.json", `{"finger": [{"cms": "Broken", "method": "regex", "location": "body", "logic": "or", "rule": ["a(b"]}]}`)
+    setFingerprintPaths(t, dir, broken)
+    if _, err := loadFingerprintSources(os.ReadFile); err == nil || !strings.HasPrefix(err.Error(), broken+": ") {
+        t.Errorf("error = %v, want it to name %s", err, broken)
+    }
+}
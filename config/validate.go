@@ -0,0 +1,334 @@
+package config
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// minRuleLength 为 body、js、css 和未指定头名称的 header 关键词的最小字节数，过短的关键词几乎会命中所有页面
+const minRuleLength = 3
+
+// 各匹配方式允许使用的 location，faviconhash 和 filehash 不使用 location
+var ruleLocations = map[string]bool{
+    "body":   true,
+    "header": true,
+    "title":  true,
+    "cert":   true,
+    "js":     true,
+    "css":    true,
+}
+
+// 版本提取规则允许使用的 location
+var versionLocations = map[string]bool{
+    "body":   true,
+    "header": true,
+    "title":  true,
+    "cert":   true,
+    "js":     true,
+    "css":    true,
+    "banner": true,
+}
+
+// Issue 为指纹库检查发现的问题，Warning 为 true 时不影响指纹加载，但指纹可能误报
+type Issue struct {
+    Index   int    // 指纹在所属列表中的下标，为 -1 时表示整个文件的问题
+    List    string // 指纹所属列表：finger 或 banner
+    CMS     string
+    Message string
+    Warning bool
+}
+
+func (issue Issue) String() string {
+    if issue.Index < 0 {
+        return issue.Message
+    }
+    if issue.List == "banner" {
+        return fmt.Sprintf("banner fingerprint #%d (%s): %s", issue.Index, issue.CMS, issue.Message)
+    }
+    return fmt.Sprintf("fingerprint #%d (%s): %s", issue.Index, issue.CMS, issue.Message)
+}
+
+// FingerprintKey 返回用于去重的键：CMS 名称（忽略大小写）和全部影响匹配的字段，规则、排除规则、文件哈希和请求头按集合比较
+func FingerprintKey(fp Fingerprint) string {
+    method := strings.ToUpper(fp.RequestMethod)
+    if method == "" {
+        method = "GET"
+    }
+    hashes := make([]string, 0, len(fp.Hashes))
+    for digest, version := range fp.Hashes {
+        hashes = append(hashes, strings.ToLower(digest)+"="+version)
+    }
+    headers := make([]string, 0, len(fp.RequestHeaders))
+    for name, value := range fp.RequestHeaders {
+        headers = append(headers, strings.ToLower(name)+": "+value)
+    }
+    versions := make([]string, len(fp.Version))
+    for i, vr := range fp.Version {
+        versions[i] = fmt.Sprintf("%s\x00%s\x00%s\x00%d", vr.Location, strings.ToLower(vr.Header), vr.Regex, vr.Group)
+    }
+    return strings.Join([]string{
+        cmsKey(fp.CMS),
+        fp.Method, fp.Location, fp.Logic, fp.Path, fp.Match, fp.Payload, method,
+        sortedKey(fp.Rule), sortedKey(fp.Not), sortedKey(hashes), sortedKey(headers),
+        // 版本规则按顺序尝试，顺序不同时提取结果可能不同
+        strings.Join(versions, "\x02"),
+    }, "\x01")
+}
+
+// sortedKey 将规则集合排序后拼接，不修改传入的切片
+func sortedKey(values []string) string {
+    sorted := append([]string(nil), values...)
+    sort.Strings(sorted)
+    return strings.Join(sorted, "\x00")
+}
+
+// ValidateFingerprintFile 检查指纹库文件，返回文件中 finger 和 banner 指纹的数量和发现的问题
+// 除了加载时就会报错的问题，还检查拼写错误的 method、location、logic，空规则，无效的图标哈希，重复指纹和过短的关键词
+func ValidateFingerprintFile(path string) (int, []Issue, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return 0, nil, err
+    }
+    return ValidateFingerprintData(data)
+}
+
+// ValidateFingerprintData 检查指纹库文件的内容，返回值与 ValidateFingerprintFile 相同
+func ValidateFingerprintData(data []byte) (int, []Issue, error) {
+    var fingerConfig FingerprintConfig
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(&fingerConfig); err != nil {
+        // 未知字段多为拼写错误，去掉限制后重新解析以继续检查其余内容
+        if !strings.HasPrefix(err.Error(), "json: unknown field") {
+            return 0, nil, err
+        }
+        fingerConfig = FingerprintConfig{}
+        if err := json.Unmarshal(data, &fingerConfig); err != nil {
+            return 0, nil, err
+        }
+        issues := []Issue{{Index: -1, Message: err.Error()}}
+        issues = append(issues, ValidateFingerprints(fingerConfig.Finger, fingerConfig.Banner)...)
+        return len(fingerConfig.Finger) + len(fingerConfig.Banner), issues, nil
+    }
+    return len(fingerConfig.Finger) + len(fingerConfig.Banner), ValidateFingerprints(fingerConfig.Finger, fingerConfig.Banner), nil
+}
+
+// ValidateFingerprints 检查 finger 和 banner 指纹列表，不修改传入的指纹
+func ValidateFingerprints(fingers []Fingerprint, banners []Fingerprint) []Issue {
+    var issues []Issue
+    seen := make(map[string]int)
+    ids := make(map[string]int)
+    for i, fp := range fingers {
+        for _, issue := range lintFingerprint(fp) {
+            issue.Index, issue.List, issue.CMS = i, "finger", fp.CMS
+            issues = append(issues, issue)
+        }
+        if fp.ID != "" {
+            if first, exists := ids[fp.ID]; exists {
+                issues = append(issues, Issue{Index: i, List: "finger", CMS: fp.CMS, Message: fmt.Sprintf("id %q is already used by fingerprint #%d", fp.ID, first)})
+            } else {
+                ids[fp.ID] = i
+            }
+        }
+        key := FingerprintKey(fp)
+        if first, exists := seen[key]; exists {
+            issues = append(issues, Issue{Index: i, List: "finger", CMS: fp.CMS, Message: fmt.Sprintf("duplicate of fingerprint #%d", first)})
+            continue
+        }
+        seen[key] = i
+    }
+
+    seen = make(map[string]int)
+    ids = make(map[string]int)
+    for i, fp := range banners {
+        if fp.ID != "" {
+            if first, exists := ids[fp.ID]; exists {
+                issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: fmt.Sprintf("id %q is already used by banner fingerprint #%d", fp.ID, first)})
+            } else {
+                ids[fp.ID] = i
+            }
+        }
+        fp.Rule = append([]string(nil), fp.Rule...)
+        fp.Not = append([]string(nil), fp.Not...)
+        fp.Version = append([]VersionRule(nil), fp.Version...)
+        key := FingerprintKey(fp)
+        if err := compileBanner(&fp); err != nil {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: err.Error()})
+        } else if message := lintVersionRules(fp.Version); message != "" {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: message})
+        }
+        if !ValidSeverity(fp.Severity) {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: severityMessage(fp.Severity)})
+        }
+        if message := lintRelations(fp); message != "" {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: message})
+        }
+        if message := lintWeight(fp.Weight); message != "" {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: message})
+        }
+        if first, exists := seen[key]; exists {
+            issues = append(issues, Issue{Index: i, List: "banner", CMS: fp.CMS, Message: fmt.Sprintf("duplicate of banner fingerprint #%d", first)})
+            continue
+        }
+        seen[key] = i
+    }
+    return issues
+}
+
+// lintFingerprint 检查单个 finger 指纹，返回的问题未填写下标和 CMS
+func lintFingerprint(fp Fingerprint) []Issue {
+    var issues []Issue
+    errorf := func(format string, args ...interface{}) {
+        issues = append(issues, Issue{Message: fmt.Sprintf(format, args...)})
+    }
+    warnf := func(format string, args ...interface{}) {
+        issues = append(issues, Issue{Message: fmt.Sprintf(format, args...), Warning: true})
+    }
+
+    if strings.TrimSpace(fp.CMS) == "" {
+        errorf("empty cms")
+    }
+    if fp.Disable {
+        // disable 条目只用于按 CMS 名称移除之前加载的指纹
+        return issues
+    }
+    if err := ValidateFingerprint(fp); err != nil {
+        errorf("%v", err)
+    }
+    if message := lintVersionRules(fp.Version); message != "" {
+        errorf("%s", message)
+    }
+    if !ValidSeverity(fp.Severity) {
+        errorf("%s", severityMessage(fp.Severity))
+    }
+    if message := lintRelations(fp); message != "" {
+        errorf("%s", message)
+    }
+    if message := lintWeight(fp.Weight); message != "" {
+        errorf("%s", message)
+    }
+    if fp.Match != "" {
+        // 设置 match 时 method/location/logic/rule 不参与匹配，表达式已在 ValidateFingerprint 中解析
+        return issues
+    }
+
+    switch fp.Method {
+    case "keyword", "regex":
+        if !ruleLocations[fp.Location] {
+            errorf("unknown location %q", fp.Location)
+        }
+        if fp.Logic != "and" && fp.Logic != "or" {
+            errorf("unknown logic %q, expected and or or", fp.Logic)
+        }
+    case "faviconhash":
+        for _, rule := range fp.Rule {
+            if _, err := strconv.ParseInt(strings.TrimSpace(rule), 10, 32); err != nil {
+                errorf("invalid favicon hash %q, expected a signed 32-bit integer", rule)
+            }
+        }
+    case "filehash":
+        if len(fp.Hashes) == 0 {
+            errorf("filehash requires at least one hash")
+        }
+        return issues
+    case "banner":
+        errorf("method banner is only supported in %s", Bannerfile)
+        return issues
+    default:
+        errorf("unknown method %q", fp.Method)
+        return issues
+    }
+
+    if len(fp.Rule) == 0 {
+        errorf("empty rule list")
+    }
+    for _, rule := range fp.Rule {
+        if rule == "" {
+            errorf("empty rule matches every response")
+        }
+    }
+    for _, rule := range fp.Not {
+        if rule == "" {
+            errorf("empty not rule excludes every response")
+        }
+    }
+    if fp.Method == "keyword" {
+        for _, rule := range shortRules(fp) {
+            warnf("rule %q is too short to be meaningful", rule)
+        }
+    }
+    return issues
+}
+
+// shortRules 返回 body、js、css 和未指定头名称的 header 关键词中过短的规则
+func shortRules(fp Fingerprint) []string {
+    var short []string
+    switch fp.Location {
+    case "body", "js", "css":
+        for _, rule := range fp.Rule {
+            if rule != "" && len(strings.TrimSpace(rule)) < minRuleLength {
+                short = append(short, rule)
+            }
+        }
+    case "header":
+        headerRules, _ := parseHeaderRules(fp.Rule)
+        for i, headerRule := range headerRules {
+            if headerRule.Name == "" && fp.Rule[i] != "" && len(strings.TrimSpace(fp.Rule[i])) < minRuleLength {
+                short = append(short, fp.Rule[i])
+            }
+        }
+    }
+    return short
+}
+
+func severityMessage(severity string) string {
+    return fmt.Sprintf("unknown severity %q, expected one of %s", severity, strings.Join(Severities, ", "))
+}
+
+// lintWeight 检查指纹权重，0 表示未填写
+func lintWeight(weight int) string {
+    if weight < 0 || weight > MaxWeight {
+        return fmt.Sprintf("weight %d out of range, expected 1 to %d", weight, MaxWeight)
+    }
+    return ""
+}
+
+// lintRelations 检查 implies 和 excludes 中的 CMS 名称，引用的产品可以不在指纹库中
+func lintRelations(fp Fingerprint) string {
+    for _, relation := range []struct {
+        field string
+        names []string
+    }{{"implies", fp.Implies}, {"excludes", fp.Excludes}} {
+        for _, name := range relation.names {
+            if strings.TrimSpace(name) == "" {
+                return fmt.Sprintf("empty name in %s", relation.field)
+            }
+            if cmsKey(name) == cmsKey(fp.CMS) {
+                return fmt.Sprintf("%s refers to the fingerprint's own cms %q", relation.field, name)
+            }
+        }
+    }
+    for _, name := range fp.Implies {
+        for _, excluded := range fp.Excludes {
+            if cmsKey(name) == cmsKey(excluded) {
+                return fmt.Sprintf("%q is both implied and excluded", name)
+            }
+        }
+    }
+    return ""
+}
+
+// lintVersionRules 检查版本提取规则的 location
+func lintVersionRules(rules []VersionRule) string {
+    for _, vr := range rules {
+        if !versionLocations[vr.Location] {
+            return fmt.Sprintf("unknown version location %q", vr.Location)
+        }
+    }
+    return ""
+}
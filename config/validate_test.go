@@ -0,0 +1,75 @@
+package config
+
+import (
+    "strings"
+    "testing"
+)
+
+// TestValidateFingerprintsDuplicates 只有影响匹配的字段全部相同时才视为重复指纹
+func TestValidateFingerprintsDuplicates(t *testing.T) {
+    base := Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"}}
+    tests := []struct {
+        name      string
+        other     Fingerprint
+        duplicate bool
+    }{
+        {"same rules in another order", Fingerprint{CMS: "demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-login", "demo-app"}}, true},
+        {"different rules", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app"}}, false},
+        {"different not", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"}, Not: []string{"demo-test"}}, false},
+        {"different version", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"},
+            Version: []VersionRule{{Location: "body", Regex: `demo-app ([\d.]+)`}}}, false},
+        {"different request method", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"}, RequestMethod: "POST"}, false},
+        {"explicit default request method", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"}, RequestMethod: "get"}, true},
+        {"different request headers", Fingerprint{CMS: "Demo", Method: "keyword", Location: "body", Logic: "or", Rule: []string{"demo-app", "demo-login"},
+            RequestHeaders: map[string]string{"X-Demo": "1"}}, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            issues := ValidateFingerprints([]Fingerprint{base, tt.other}, nil)
+            if got := hasIssue(issues, "duplicate of fingerprint #0"); got != tt.duplicate {
+                t.Errorf("duplicate = %v, want %v, issues: %v", got, tt.duplicate, issues)
+            }
+        })
+    }
+}
+
+// TestValidateFingerprintsFileHashes 同一路径上哈希不同的 filehash 指纹不是重复指纹
+func TestValidateFingerprintsFileHashes(t *testing.T) {
+    md5a, md5b := strings.Repeat("a", 32), strings.Repeat("b", 32)
+    fingers := []Fingerprint{
+        {CMS: "Demo", Method: "filehash", Path: "/static/app.js", Hashes: map[string]string{md5a: "1.0"}},
+        {CMS: "Demo", Method: "filehash", Path: "/static/app.js", Hashes: map[string]string{md5b: "2.0"}},
+    }
+    if issues := ValidateFingerprints(fingers, nil); len(issues) != 0 {
+        t.Errorf("unexpected issues: %v", issues)
+    }
+    fingers[1].Hashes = map[string]string{md5a: "1.0"}
+    if issues := ValidateFingerprints(fingers, nil); !hasIssue(issues, "duplicate of fingerprint #0") {
+        t.Errorf("identical filehash fingerprints not reported: %v", issues)
+    }
+}
+
+func hasIssue(issues []Issue, message string) bool {
+    for _, issue := range issues {
+        if issue.Message == message {
+            return true
+        }
+    }
+    return false
+}
+
+// TestValidateFingerprintMatchNot 设置了 match 的指纹不能使用 not，排除条件应写在表达式中
+func TestValidateFingerprintMatchNot(t *testing.T) {
+    fp := Fingerprint{CMS: "Demo", Match: `body="demo-app"`, Not: []string{"demo-test"}}
+    issues := ValidateFingerprints([]Fingerprint{fp}, nil)
+    if len(issues) != 1 || !strings.Contains(issues[0].Message, "not is not supported with match") {
+        t.Errorf("issues = %v, want the not rule rejected", issues)
+    }
+    fp.Not, fp.Match = nil, `body="demo-app" && body!="demo-test"`
+    if issues := ValidateFingerprints([]Fingerprint{fp}, nil); len(issues) != 0 {
+        t.Errorf("unexpected issues: %v", issues)
+    }
+    if _, err := ParseFingerprintConfig([]byte(`{"finger": [{"cms": "Demo", "match": "body=\"demo-app\"", "not": ["demo-test"]}]}`)); err == nil {
+        t.Error("library with not on a match fingerprint loaded without error")
+    }
+}
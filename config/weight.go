@@ -0,0 +1,39 @@
+package config
+
+// MaxWeight 为指纹权重和可信度的最大值
+const MaxWeight = 100
+
+// FingerprintWeight 返回指纹单独命中时的可信度，未填写 weight 时按匹配方式取默认值
+// 哈希类规则几乎不会误报，单个正文关键词最容易误报，and 逻辑的每条额外规则提高10，最高为90
+func FingerprintWeight(fp Fingerprint) int {
+    if fp.Weight > 0 {
+        return fp.Weight
+    }
+    var weight int
+    switch {
+    case fp.Match != "":
+        return 60
+    case fp.Method == "filehash":
+        return 90
+    case fp.Method == "faviconhash":
+        return 80
+    case fp.Method == "banner", fp.Location == "cert":
+        weight = 70
+    case fp.Method == "regex", fp.Location == "header", fp.Location == "title":
+        weight = 50
+    default:
+        weight = 40
+    }
+    if fp.Logic == "and" && len(fp.Rule) > 1 {
+        weight += 10 * (len(fp.Rule) - 1)
+    }
+    if weight > 90 {
+        weight = 90
+    }
+    return weight
+}
+
+// CombineConfidence 将两个独立的命中依据合并为一个可信度，两者都误报的概率为各自误报概率的乘积
+func CombineConfidence(a int, b int) int {
+    return MaxWeight - (MaxWeight-a)*(MaxWeight-b)/MaxWeight
+}
@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+// TestFingerprintWeight 未填写 weight 时按匹配方式取默认可信度，and 逻辑的额外规则提高可信度，最高为90
+func TestFingerprintWeight(t *testing.T) {
+    tests := []struct {
+        name string
+        fp   Fingerprint
+        want int
+    }{
+        {"explicit weight", Fingerprint{Method: "keyword", Location: "body", Weight: 95}, 95},
+        {"body keyword", Fingerprint{Method: "keyword", Location: "body", Logic: "or", Rule: []string{"a", "b", "c"}}, 40},
+        {"body keywords with and", Fingerprint{Method: "keyword", Location: "body", Logic: "and", Rule: []string{"a", "b", "c"}}, 60},
+        {"header keyword", Fingerprint{Method: "keyword", Location: "header", Logic: "or", Rule: []string{"Server: nginx"}}, 50},
+        {"title keyword", Fingerprint{Method: "keyword", Location: "title", Logic: "and", Rule: []string{"a", "b"}}, 60},
+        {"body regex", Fingerprint{Method: "regex", Location: "body", Logic: "or", Rule: []string{"a"}}, 50},
+        {"cert", Fingerprint{Method: "keyword", Location: "cert", Logic: "and", Rule: []string{"a", "b"}}, 80},
+        {"banner", Fingerprint{Method: "banner", Logic: "and", Rule: []string{"a", "b", "c", "d"}}, 90},
+        {"and is capped", Fingerprint{Method: "keyword", Location: "body", Logic: "and", Rule: []string{"a", "b", "c", "d", "e", "f", "g"}}, 90},
+        {"faviconhash", Fingerprint{Method: "faviconhash", Rule: []string{"1", "2"}}, 80},
+        {"filehash", Fingerprint{Method: "filehash", Path: "/app.js"}, 90},
+        {"match expression", Fingerprint{Method: "keyword", Location: "body", Logic: "and", Rule: []string{"a", "b"}, Match: `body="a" && body="b"`}, 60},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := FingerprintWeight(tt.fp); got != tt.want {
+                t.Errorf("weight = %d, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestCombineConfidence 两个独立依据合并后的可信度不低于任一方，不超过 MaxWeight，与合并顺序无关
+func TestCombineConfidence(t *testing.T) {
+    tests := []struct {
+        a, b int
+        want int
+    }{
+        {40, 40, 64},
+        {50, 80, 90},
+        {90, 90, 99},
+        {0, 70, 70},
+        {100, 40, 100},
+        {99, 99, 100},
+    }
+    for _, tt := range tests {
+        if got := CombineConfidence(tt.a, tt.b); got != tt.want {
+            t.Errorf("CombineConfidence(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+        if got := CombineConfidence(tt.b, tt.a); got != tt.want {
+            t.Errorf("CombineConfidence(%d, %d) = %d, want %d", tt.b, tt.a, got, tt.want)
+        }
+    }
+}
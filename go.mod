@@ -13,6 +13,8 @@ require (
 	github.com/twmb/murmur3 v1.1.8
 	github.com/vincent-petithory/dataurl v1.0.0
 	golang.org/x/net v0.24.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -23,5 +25,4 @@ require (
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/crypto v0.22.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 )
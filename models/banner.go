@@ -0,0 +1,193 @@
+package models
+
+import (
+    "errors"
+    "io"
+    "net"
+    "strings"
+    "syscall"
+    "time"
+    "unicode"
+
+    "hfinger/config"
+    "hfinger/logger"
+    "hfinger/output"
+)
+
+var (
+    bannerMode        bool                     // 是否将所有目标作为 TCP 服务识别
+    bannerDialTimeout = 5 * time.Second        // 建立 TCP 连接的超时时间
+    bannerReadTimeout = 3 * time.Second        // 等待服务返回第一段数据的超时时间
+    bannerIdleTimeout = 500 * time.Millisecond // 收到数据后等待后续数据的超时时间
+    bannerMaxSize     = 4096                   // 读取的最大字节数
+)
+
+// SetBannerMode 设置是否将 -u、-f 指定的所有目标作为 host:port 形式的 TCP 服务识别
+func SetBannerMode(enabled bool) {
+    bannerMode = enabled
+}
+
+// ProcessTarget 根据目标形式选择识别方式，tcp:// 开头或开启 banner 模式时识别 TCP 服务
+func ProcessTarget(target string) {
+    if bannerMode || strings.HasPrefix(target, "tcp://") {
+        ProcessBanner(target)
+        return
+    }
+    ProcessURL(target)
+}
+
+// grabBanner 连接服务，payload 不为空时先发送载荷，然后读取服务返回的内容
+func grabBanner(address string, payload []byte) ([]byte, error) {
+    conn, err := net.DialTimeout("tcp", address, bannerDialTimeout)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+
+    if len(payload) > 0 {
+        conn.SetWriteDeadline(time.Now().Add(bannerReadTimeout))
+        if _, err := conn.Write(payload); err != nil {
+            return nil, err
+        }
+    }
+
+    buf := make([]byte, bannerMaxSize)
+    n := 0
+    conn.SetReadDeadline(time.Now().Add(bannerReadTimeout))
+    for n < len(buf) {
+        m, err := conn.Read(buf[n:])
+        n += m
+        if err != nil {
+            var netErr net.Error
+            if n > 0 || (errors.As(err, &netErr) && netErr.Timeout()) {
+                // 服务不主动发送数据或已发送完毕，不作为错误处理
+                break
+            }
+            if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+                // 连接已建立，服务未返回内容就关闭或重置连接，按空 banner 处理，仍需继续发送探测载荷
+                break
+            }
+            return nil, err
+        }
+        conn.SetReadDeadline(time.Now().Add(bannerIdleTimeout))
+    }
+    return buf[:n], nil
+}
+
+// matchBannerProducts 使用 fingerConfig 中指定的 banner 指纹匹配服务返回的内容
+func matchBannerProducts(fingerConfig *config.FingerprintConfig, banner []byte, fingers []int) []matchedProduct {
+    var products []matchedProduct
+    if len(banner) == 0 {
+        return products
+    }
+    for _, i := range fingers {
+        fingerprint := fingerConfig.Banner[i]
+        if matchKeywords(banner, nil, "", "", nil, fingerprint) {
+            version := extractVersion(banner, nil, "", "", fingerprint)
+            products = mergeProducts(products, []matchedProduct{{
+                CMS:        fingerprint.CMS,
+                Version:    version,
+                Evidence:   collectEvidence(banner, nil, "", "", nil, fingerprint),
+                Confidence: config.FingerprintWeight(fingerprint),
+            }})
+        }
+    }
+    return products
+}
+
+// printableText 将服务返回的内容或命中片段转换为单行文本，连续的不可打印字符和换行替换为一个空格
+func printableText(banner []byte) string {
+    var sb strings.Builder
+    separated := false
+    for _, r := range strings.ToValidUTF8(string(banner), "\uFFFD") {
+        if r == '\uFFFD' || !unicode.IsPrint(r) {
+            separated = true
+            continue
+        }
+        if separated && sb.Len() > 0 {
+            sb.WriteByte(' ')
+        }
+        separated = false
+        sb.WriteRune(r)
+    }
+    line := sb.String()
+    if runes := []rune(line); len(runes) > 100 {
+        line = string(runes[:100]) + "..."
+    }
+    if line == "" {
+        return "None"
+    }
+    return line
+}
+
+// identifyBanner 先匹配服务主动发送的欢迎信息，没有命中时依次发送各个探测载荷，返回命中的内容和产品
+// 没有命中时返回欢迎信息，只有连接失败时返回错误
+func identifyBanner(fingerConfig *config.FingerprintConfig, address string) ([]byte, []matchedProduct, error) {
+    greeting, err := grabBanner(address, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    if products := matchBannerProducts(fingerConfig, greeting, fingerConfig.GreetingFingers); len(products) > 0 {
+        return greeting, products, nil
+    }
+    for _, probe := range fingerConfig.BannerProbes {
+        response, err := grabBanner(address, probe.Payload)
+        if err != nil {
+            continue
+        }
+        if products := matchBannerProducts(fingerConfig, response, probe.Fingers); len(products) > 0 {
+            return response, products, nil
+        }
+    }
+    return greeting, nil, nil
+}
+
+// ProcessBanner 识别 host:port 形式的 TCP 服务
+// 先读取连接后的欢迎信息，没有命中时再依次发送各个指纹声明的探测载荷
+func ProcessBanner(target string) {
+    address := strings.TrimSuffix(strings.TrimPrefix(target, "tcp://"), "/")
+    if _, _, err := net.SplitHostPort(address); err != nil {
+        logger.Error("Error: Invalid TCP target %s: %v", target, err)
+        return
+    }
+    targetURL := "tcp://" + address
+    fingerConfig := currentFingerprints()
+    if len(fingerConfig.Banner) == 0 {
+        logger.Error("Error: No banner fingerprints loaded from %s", config.Bannerfullpath)
+        return
+    }
+
+    banner, products, err := identifyBanner(fingerConfig, address)
+    if err != nil {
+        logger.PrintByLevel(err, targetURL)
+        return
+    }
+
+    summary := printableText(banner)
+    if len(products) == 0 {
+        logger.Info("[%s] [Not Matched] [%s]", targetURL, summary)
+        return
+    }
+
+    var results []config.Result
+    for _, product := range products {
+        result := withEvidence(config.Result{
+            URL:        targetURL,
+            CMS:        product.CMS,
+            Version:    product.Version,
+            Server:     "None",
+            Title:      "None",
+            Banner:     summary,
+            Confidence: product.Confidence,
+        }, product.Evidence)
+        results = append(results, withProductInfo(result, fingerConfig))
+    }
+
+    outputLock.Lock()
+    defer outputLock.Unlock()
+    for _, result := range filterResults(resolveRelations(fingerConfig, results)) {
+        logger.Success("[%s] [%s] [%s]%s", targetURL, resultLabel(result), summary, evidenceLine(result))
+        output.AddResults(result)
+    }
+}
+
@@ -0,0 +1,134 @@
+package models
+
+import (
+    "bytes"
+    "net"
+    "testing"
+    "time"
+
+    "hfinger/config"
+)
+
+const testBannerLibrary = `{"banner": [{
+    "cms": "OpenSSH",
+    "method": "banner",
+    "logic": "and",
+    "rule": ["SSH-","OpenSSH"],
+    "version": [{"location": "banner", "regex": "OpenSSH_([\\w.]+)"}]
+}, {
+    "cms": "Redis",
+    "method": "banner",
+    "logic": "or",
+    "rule": ["+PONG"],
+    "payload": "PING\r\n"
+}]}`
+
+// loadTestBanners 解析测试用的 banner 指纹库，并缩短读取超时
+func loadTestBanners(t *testing.T) *config.FingerprintConfig {
+    fingerConfig := &config.FingerprintConfig{}
+    if err := config.ParseBannerConfig(fingerConfig, []byte(testBannerLibrary)); err != nil {
+        t.Fatal(err)
+    }
+    readTimeout, idleTimeout := bannerReadTimeout, bannerIdleTimeout
+    bannerReadTimeout, bannerIdleTimeout = 300*time.Millisecond, 50*time.Millisecond
+    t.Cleanup(func() { bannerReadTimeout, bannerIdleTimeout = readTimeout, idleTimeout })
+    return fingerConfig
+}
+
+// answerPing 等待客户端发送 PING，收到后返回 +PONG，idle 时间内没有收到数据时返回 false
+func answerPing(conn net.Conn, idle time.Duration) bool {
+    buf := make([]byte, 64)
+    conn.SetReadDeadline(time.Now().Add(idle))
+    n, err := conn.Read(buf)
+    if err != nil {
+        return false
+    }
+    if bytes.Contains(buf[:n], []byte("PING")) {
+        conn.Write([]byte("+PONG\r\n"))
+    }
+    return true
+}
+
+func TestIdentifyBanner(t *testing.T) {
+    fingerConfig := loadTestBanners(t)
+    tests := []struct {
+        name    string
+        handle  func(net.Conn)
+        cms     string
+        version string
+        banner  string
+    }{
+        {
+            name:    "greeting",
+            handle:  func(conn net.Conn) { conn.Write([]byte("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1\r\n")) },
+            cms:     "OpenSSH",
+            version: "8.9p1",
+            banner:  "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1\r\n",
+        },
+        {
+            name:   "probe response",
+            handle: func(conn net.Conn) { answerPing(conn, time.Second) },
+            cms:    "Redis",
+            banner: "+PONG\r\n",
+        },
+        {
+            // 服务在欢迎信息阶段未收到数据就关闭连接，仍需发送探测载荷
+            name: "closed idle greeting",
+            handle: func(conn net.Conn) {
+                answerPing(conn, 20*time.Millisecond)
+            },
+            cms:    "Redis",
+            banner: "+PONG\r\n",
+        },
+        {
+            name: "reset idle greeting",
+            handle: func(conn net.Conn) {
+                if !answerPing(conn, 20*time.Millisecond) {
+                    conn.(*net.TCPConn).SetLinger(0)
+                }
+            },
+            cms:    "Redis",
+            banner: "+PONG\r\n",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            address := startTCPServer(t, tt.handle)
+            banner, products, err := identifyBanner(fingerConfig, address)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if string(banner) != tt.banner {
+                t.Errorf("banner = %q, want %q", banner, tt.banner)
+            }
+            if len(products) != 1 || products[0].CMS != tt.cms || products[0].Version != tt.version {
+                t.Errorf("products = %+v, want %s %s", products, tt.cms, tt.version)
+            }
+        })
+    }
+}
+
+func TestIdentifyBannerNotMatched(t *testing.T) {
+    fingerConfig := loadTestBanners(t)
+    address := startTCPServer(t, func(conn net.Conn) { conn.Write([]byte("220 unknown service ready\r\n")) })
+    banner, products, err := identifyBanner(fingerConfig, address)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(products) != 0 || string(banner) != "220 unknown service ready\r\n" {
+        t.Errorf("got banner %q and products %+v, want the greeting and no products", banner, products)
+    }
+}
+
+func TestIdentifyBannerClosedPort(t *testing.T) {
+    fingerConfig := loadTestBanners(t)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    address := listener.Addr().String()
+    listener.Close()
+    if _, _, err := identifyBanner(fingerConfig, address); err == nil {
+        t.Error("expected an error for a closed port")
+    }
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+    "crypto/md5"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+
+    "hfinger/config"
+)
+
+// snippetContext 为命中片段在命中内容前后各保留的字节数
+const snippetContext = 40
+
+var verbose bool // 是否在 [+] 行下输出命中依据
+
+// SetVerbose 设置是否在控制台输出每个结果命中的指纹 ID、规则和内容片段
+func SetVerbose(enabled bool) {
+    verbose = enabled
+}
+
+// matchEvidence 为指纹命中的依据
+type matchEvidence struct {
+    FingerprintID string
+    Method        string
+    Location      string
+    Rules         []string
+    Snippet       string
+}
+
+// collectEvidence 在指纹命中后找出命中的规则和命中位置附近的内容，只对命中的指纹调用
+func collectEvidence(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingerprint config.Fingerprint) matchEvidence {
+    evidence := matchEvidence{FingerprintID: fingerprint.ID, Method: fingerprint.Method, Location: fingerprint.Location}
+    if fingerprint.Expr != nil {
+        evidence.Method, evidence.Location = "match", ""
+        exprEvidence(body, header, title, cert, icons, fingerprint.Expr, &evidence)
+        return evidence
+    }
+    ruleEvidence(body, header, title, cert, icons, fingerprint, &evidence)
+    return evidence
+}
+
+// exprEvidence 收集 match 表达式中求值为真且未被取反的条件，location 和片段取第一个命中的条件
+func exprEvidence(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, expr *config.Expr, evidence *matchEvidence) {
+    switch expr.Op {
+    case "and", "or":
+        exprEvidence(body, header, title, cert, icons, expr.Left, evidence)
+        exprEvidence(body, header, title, cert, icons, expr.Right, evidence)
+    case "leaf":
+        if !matchKeywords(body, header, title, cert, icons, *expr.Leaf) {
+            return
+        }
+        evidence.Rules = append(evidence.Rules, expr.Cond)
+        if evidence.Location == "" {
+            var leaf matchEvidence
+            ruleEvidence(body, header, title, cert, icons, *expr.Leaf, &leaf)
+            evidence.Location, evidence.Snippet = expr.Leaf.Location, leaf.Snippet
+        }
+    }
+}
+
+// ruleEvidence 收集单个指纹命中的规则，片段取第一条命中规则附近的内容
+func ruleEvidence(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingerprint config.Fingerprint, evidence *matchEvidence) {
+    switch fingerprint.Method {
+    case "faviconhash":
+        // faviconhash 和 filehash 不使用 location
+        evidence.Location = ""
+        if icon, ok := matchedIcon(icons, fingerprint); ok {
+            evidence.Rules, evidence.Snippet = []string{icon.Hash}, icon.Hash
+        }
+        return
+    case "filehash":
+        evidence.Location = ""
+        if digest := matchedDigest(body, fingerprint); digest != "" {
+            evidence.Rules, evidence.Snippet = []string{digest}, digest
+        }
+        return
+    }
+
+    var content string
+    switch fingerprint.Location {
+    case "body", "js", "css", "banner":
+        content = string(body)
+    case "title":
+        content = title
+    case "cert":
+        content = cert
+    case "header":
+        for i, rule := range fingerprint.Rule {
+            if name, value, ok := findHeaderRule(header, fingerprint, i); ok {
+                evidence.Rules = append(evidence.Rules, rule)
+                if evidence.Snippet == "" {
+                    evidence.Snippet = printableText([]byte(name + ": " + value))
+                }
+            }
+        }
+        return
+    default:
+        return
+    }
+    for i, rule := range fingerprint.Rule {
+        start, end, ok := ruleSpan(content, fingerprint, i)
+        if !ok {
+            continue
+        }
+        evidence.Rules = append(evidence.Rules, rule)
+        if evidence.Snippet == "" {
+            evidence.Snippet = contentSnippet(content, start, end)
+        }
+    }
+}
+
+// ruleSpan 返回单条规则在内容中第一次命中的位置
+func ruleSpan(content string, fingerprint config.Fingerprint, index int) (int, int, bool) {
+    if fingerprint.Method == "regex" {
+        if index >= len(fingerprint.Regexps) {
+            return 0, 0, false
+        }
+        loc := fingerprint.Regexps[index].FindStringIndex(content)
+        if loc == nil {
+            return 0, 0, false
+        }
+        return loc[0], loc[1], true
+    }
+    start := strings.Index(content, fingerprint.Rule[index])
+    if start < 0 {
+        return 0, 0, false
+    }
+    return start, start + len(fingerprint.Rule[index]), true
+}
+
+// contentSnippet 截取命中位置前后的内容，边界对齐到完整的 UTF-8 字符
+func contentSnippet(content string, start int, end int) string {
+    lo, hi := start-snippetContext, end+snippetContext
+    if lo < 0 {
+        lo = 0
+    }
+    if hi > len(content) {
+        hi = len(content)
+    }
+    for lo > 0 && !utf8.RuneStart(content[lo]) {
+        lo--
+    }
+    for hi < len(content) && !utf8.RuneStart(content[hi]) {
+        hi++
+    }
+    return printableText([]byte(content[lo:hi]))
+}
+
+// matchedDigest 返回资源命中 filehash 指纹的 md5 或 sha256 摘要
+func matchedDigest(body []byte, fingerprint config.Fingerprint) string {
+    md5Sum := md5.Sum(body)
+    if digest := hex.EncodeToString(md5Sum[:]); hasDigest(fingerprint, digest) {
+        return digest
+    }
+    sha256Sum := sha256.Sum256(body)
+    if digest := hex.EncodeToString(sha256Sum[:]); hasDigest(fingerprint, digest) {
+        return digest
+    }
+    return ""
+}
+
+func hasDigest(fingerprint config.Fingerprint, digest string) bool {
+    _, ok := fingerprint.Hashes[digest]
+    return ok
+}
+
+// withEvidence 将命中依据填入结果
+func withEvidence(result config.Result, evidence matchEvidence) config.Result {
+    result.FingerprintID = evidence.FingerprintID
+    result.MatchMethod = evidence.Method
+    result.MatchLocation = evidence.Location
+    result.MatchedRules = evidence.Rules
+    result.Evidence = evidence.Snippet
+    return result
+}
+
+// evidenceLine 开启 verbose 时返回附加在 [+] 行下的命中依据，与 [+] 行一起输出避免被其它目标的输出打断
+func evidenceLine(result config.Result) string {
+    if !verbose || result.FingerprintID == "" {
+        return ""
+    }
+    rules := make([]string, len(result.MatchedRules))
+    for i, rule := range result.MatchedRules {
+        rules[i] = strconv.Quote(rule)
+    }
+    location := result.MatchMethod
+    if result.MatchLocation != "" {
+        location += " " + result.MatchLocation
+    }
+    return fmt.Sprintf("\n    ↳ [%s] [%s] [%s] [%s] [confidence %d]", result.FingerprintID, location, strings.Join(rules, ", "), result.Evidence, result.Confidence)
+}
@@ -8,13 +8,13 @@ import (
     "github.com/sirupsen/logrus"
     "github.com/twmb/murmur3"
     "github.com/vincent-petithory/dataurl"
-    "golang.org/x/net/html"
-    "golang.org/x/net/html/atom"
     "io"
     "net/http"
     "net/url"
     "os"
     "strings"
+
+    "hfinger/utils"
 )
 
 // mmh3Hash32 generate icon hash
@@ -28,7 +28,10 @@ func mmh3Hash32(raw []byte) string {
             buffer.WriteByte('\n')
         }
     }
-    buffer.WriteByte('\n')
+    // same as python base64.encodebytes: no extra newline after a full line or for empty data
+    if len(bckd)%76 != 0 {
+        buffer.WriteByte('\n')
+    }
     return fmt.Sprintf("%d", int32(murmur3.Sum32(buffer.Bytes())))
 }
 
@@ -90,57 +93,102 @@ func fetchURLContent(iconUrl string) (data []byte, contentType string, err error
     return
 }
 
-// ExtractIconFromHtml extract link icon from html
-func ExtractIconFromHtml(data []byte) string {
-    r := bytes.NewReader(data)
-    z := html.NewTokenizer(r)
-
-tokenize:
-    for {
-        tt := z.Next()
-
-        var href string
-        var isIconLink bool
-
-        switch tt {
-        case html.ErrorToken:
-            // End of the document, we're done
-            return ""
-        case html.StartTagToken, html.SelfClosingTagToken:
-            name, hasAttr := z.TagName()
-            if atom.Link == atom.Lookup(name) {
-                for hasAttr {
-                    var k, v []byte
-                    k, v, hasAttr = z.TagAttr()
-                    switch string(k) {
-                    case "rel":
-                        cs := strings.Split(strings.ToLower(string(v)), " ")
-                        for _, c := range cs {
-                            if strings.EqualFold(c, "icon") {
-                                isIconLink = true
-                                break
-                            }
-                        }
-
-                        if !isIconLink {
-                            continue tokenize
-                        }
-                    case "href":
-                        href = string(v)
-                    }
-                }
+// maxIconCandidates 为单个页面最多下载并计算哈希的图标数量
+const maxIconCandidates = 8
+
+// iconInfo 记录一个图标地址及其 mmh3 哈希
+type iconInfo struct {
+    URL  string
+    Hash string
+}
+
+// iconCandidates collect icon urls declared by the page in document order,
+// then the icons listed in its manifests, and finally the default /favicon.ico
+// relative urls are resolved against the page, data: uris are kept as is
+func iconCandidates(page *url.URL, data []byte, fetch func(string) []byte) []string {
+    var candidates []string
+    seen := make(map[string]bool)
+    add := func(base *url.URL, href string) {
+        if len(candidates) >= maxIconCandidates {
+            return
+        }
+        if !strings.HasPrefix(href, "data:") {
+            rel, err := url.Parse(href)
+            if err != nil {
+                logrus.Debug("icon url is not valid:", err)
+                return
             }
+            href = base.ResolveReference(rel).String()
+        }
+        if !seen[href] {
+            seen[href] = true
+            candidates = append(candidates, href)
+        }
+    }
+
+    icons, manifests := utils.FetchIconCandidates(data)
+    for _, href := range icons {
+        add(page, href)
+    }
+    for _, href := range manifests {
+        rel, err := url.Parse(href)
+        if err != nil {
+            continue
+        }
+        manifestURL := page.ResolveReference(rel)
+        if manifest := fetch(manifestURL.String()); manifest != nil {
+            for _, src := range utils.ParseManifestIcons(manifest) {
+                add(manifestURL, src)
+            }
+        }
+    }
+    add(page, "/favicon.ico")
+    return candidates
+}
+
+// decodeDataIcon decode the image in a data: uri, return nil if it is not an image
+func decodeDataIcon(href string) []byte {
+    dataURL, err := dataurl.DecodeString(href)
+    if err != nil {
+        logrus.Debug("icon data uri is not valid:", err)
+        return nil
+    }
+    if !isImageContent(dataURL.MediaType.ContentType()) {
+        return nil
+    }
+    return dataURL.Data
+}
+
+// fetchIcons 下载页面的全部图标候选并计算哈希，下载失败或内容为空的图标被忽略
+func fetchIcons(pageURL string, body []byte, cache *assetCache) []iconInfo {
+    page, err := url.Parse(pageURL)
+    if err != nil {
+        return nil
+    }
+    fetch := func(u string) []byte {
+        return cache.fetch(u, maxResourceSize)
+    }
+    var icons []iconInfo
+    for _, candidate := range iconCandidates(page, body, fetch) {
+        var data []byte
+        if strings.HasPrefix(candidate, "data:") {
+            data = decodeDataIcon(candidate)
+        } else {
+            data = fetch(candidate)
         }
-        if isIconLink && href != "" {
-            return href
+        if len(data) == 0 {
+            continue
         }
+        icons = append(icons, iconInfo{URL: candidate, Hash: mmh3Hash32(data)})
     }
+    return icons
 }
 
 // IconHash
 // if url is a local icon file, then calc the hash
 // if url is remote icon url, the download and calc the hash
-// if url is web homepage, then try to parse favicon url and download it, then calc the hash
+// if url is web homepage, then try every icon the page declares and the default favicon.ico,
+// and return the hash of the first one that can be downloaded
 func IconHash(iconUrl string) (hash string, err error) {
     // check if local file
     _, err = os.Stat(iconUrl)
@@ -148,10 +196,6 @@ func IconHash(iconUrl string) (hash string, err error) {
         // 存在
         return fileIconHash(iconUrl)
     }
-    //// 还有不存在的错误？
-    //if !errors.Is(err, os.ErrNotExist) {
-    //    return
-    //}
 
     if !strings.Contains(iconUrl, "://") {
         err = errors.New("icon url is not valid url")
@@ -172,50 +216,38 @@ func IconHash(iconUrl string) (hash string, err error) {
         return
     }
 
-    // parse icon url
-    var parsedURL string
+    // parse icon urls
+    var page []byte
     if strings.Contains(contentType, "html") {
         logrus.Debug("try to parse favicon url")
-        parsedURL = ExtractIconFromHtml(data)
+        page = data
     }
-
-    if len(parsedURL) > 0 {
-        logrus.Debug("parsed favicon url from html:", parsedURL)
+    fetch := func(u string) []byte {
+        content, _, errF := fetchURLContent(u)
+        if errF != nil {
+            return nil
+        }
+        return content
+    }
+    for _, candidate := range iconCandidates(u, page, fetch) {
+        logrus.Debug("try icon:", candidate)
 
         // inner base64
-        if strings.HasPrefix(parsedURL, "data:image") {
-            var dataURL *dataurl.DataURL
-            dataURL, err = dataurl.DecodeString(parsedURL)
-            if err != nil {
-                return
-            }
-            if isImageContent(dataURL.MediaType.ContentType()) {
-                hash = mmh3Hash32(dataURL.Data)
+        if strings.HasPrefix(candidate, "data:") {
+            if icon := decodeDataIcon(candidate); icon != nil {
+                hash = mmh3Hash32(icon)
                 return
             }
+            continue
         }
 
-        if rel, errP := url.Parse(parsedURL); errP == nil {
-            newURL := u.ResolveReference(rel)
-            data, contentType, err = fetchURLContent(newURL.String())
-            if isImageContent(contentType) {
-                hash = mmh3Hash32(data)
-                return
-            }
-        } else {
-            logrus.Debug("parsed favicon url is not valid:", errP)
+        data, contentType, err = fetchURLContent(candidate)
+        if err == nil && isImageContent(contentType) {
+            hash = mmh3Hash32(data)
+            return
         }
     }
 
-    // just try default favicon.ico
-    logrus.Debug("try default favicon.ico")
-    defaultIconURL := u.Scheme + "://" + u.Host + "/favicon.ico"
-    data, contentType, err = fetchURLContent(defaultIconURL)
-    if isImageContent(contentType) {
-        hash = mmh3Hash32(data)
-        return
-    }
-
     err = errors.New("can not find any icon")
     return
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+    "bytes"
+    "fmt"
+    "net/url"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/twmb/murmur3"
+)
+
+// TestIconCandidates 按页面声明、manifest、默认 favicon.ico 的顺序收集图标，去重并限制数量
+func TestIconCandidates(t *testing.T) {
+    page, _ := url.Parse("http://example.com/app/index.html")
+    manifests := map[string]string{
+        "http://example.com/static/site.webmanifest": `{"icons": [{"src": "icons/192.png"}, {"src": "/favicon.ico"}]}`,
+    }
+    fetch := func(u string) []byte {
+        if manifest, ok := manifests[u]; ok {
+            return []byte(manifest)
+        }
+        return nil
+    }
+    tests := []struct {
+        name string
+        body string
+        want []string
+    }{
+        {
+            name: "no icons",
+            body: `<html><head><title>demo</title></head></html>`,
+            want: []string{"http://example.com/favicon.ico"},
+        },
+        {
+            name: "declared icons and manifest",
+            body: `<html><head>
+                <link rel="shortcut icon" href="img/a.ico">
+                <link rel="apple-touch-icon" href="//cdn.example.com/b.png">
+                <link rel="manifest" href="/static/site.webmanifest">
+                <link rel="icon" href="data:image/png;base64,iVBORw0KGgo=">
+                <link rel="icon" href="img/a.ico">
+            </head></html>`,
+            want: []string{
+                "http://example.com/app/img/a.ico",
+                "http://cdn.example.com/b.png",
+                "data:image/png;base64,iVBORw0KGgo=",
+                "http://example.com/static/icons/192.png",
+                "http://example.com/favicon.ico",
+            },
+        },
+        {
+            name: "unreachable manifest",
+            body: `<link rel="manifest" href="/missing.json"><link rel="icon" href="/a.png">`,
+            want: []string{"http://example.com/a.png", "http://example.com/favicon.ico"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := iconCandidates(page, []byte(tt.body), fetch); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+
+    var links strings.Builder
+    for i := 0; i < maxIconCandidates+2; i++ {
+        fmt.Fprintf(&links, `<link rel="icon" href="/icon%d.png">`, i)
+    }
+    if got := iconCandidates(page, []byte(links.String()), fetch); len(got) != maxIconCandidates {
+        t.Errorf("collected %d candidates, want at most %d", len(got), maxIconCandidates)
+    }
+}
+
+// TestMmh3Hash32 图标哈希与 FOFA、Shodan 的计算方式一致：按 Python base64.encodebytes 每 76 个字符换行后计算 mmh3，输出有符号整数
+func TestMmh3Hash32(t *testing.T) {
+    // mmh3 文档中的参考值，确认哈希为 seed 0 的 MurmurHash3 x86_32
+    if got := int32(murmur3.Sum32([]byte("foo"))); got != -156908512 {
+        t.Fatalf("mmh3(foo) = %d, want -156908512", got)
+    }
+    line := strings.Repeat("/", 76)
+    tests := []struct {
+        name    string
+        data    []byte
+        encoded string
+    }{
+        {"short", []byte("abc"), "YWJj\n"},
+        {"one full line", bytes.Repeat([]byte{0xff}, 57), line + "\n"},
+        {"two lines", bytes.Repeat([]byte{0xff}, 58), line + "\n/w==\n"},
+        {"two full lines", bytes.Repeat([]byte{0xff}, 114), line + "\n" + line + "\n"},
+    }
+    for _, tt := range tests {
+        want := fmt.Sprintf("%d", int32(murmur3.Sum32([]byte(tt.encoded))))
+        if got := mmh3Hash32(tt.data); got != want {
+            t.Errorf("%s: mmh3Hash32 = %s, want %s", tt.name, got, want)
+        }
+    }
+    // Shodan 中空图标的 http.favicon.hash 为 0
+    if got := mmh3Hash32(nil); got != "0" {
+        t.Errorf("hash of an empty icon = %s, want 0", got)
+    }
+}
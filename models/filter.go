@@ -0,0 +1,84 @@
+package models
+
+import (
+    "strings"
+
+    "hfinger/config"
+)
+
+var (
+    includeFilters []string // 只输出类别或标签在其中的结果，为空时不限制
+    excludeFilters []string // 不输出类别或标签在其中的结果
+    minConfidence  int      // 不输出可信度低于该值的结果
+)
+
+// SetResultFilter 设置按类别或标签筛选控制台和输出文件中的结果，忽略大小写
+func SetResultFilter(include []string, exclude []string) {
+    includeFilters = normalizeFilters(include)
+    excludeFilters = normalizeFilters(exclude)
+}
+
+func normalizeFilters(values []string) []string {
+    var filters []string
+    for _, value := range values {
+        if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+            filters = append(filters, value)
+        }
+    }
+    return filters
+}
+
+// SetMinConfidence 设置控制台和输出文件中结果的最低可信度
+func SetMinConfidence(confidence int) {
+    minConfidence = confidence
+}
+
+// withProductInfo 将指纹库中该产品的类别、标签、厂商和关注程度填入结果
+func withProductInfo(result config.Result, fingerConfig *config.FingerprintConfig) config.Result {
+    info := fingerConfig.Product(result.CMS)
+    result.Category = info.Category
+    result.Tags = info.Tags
+    result.Vendor = info.Vendor
+    result.Severity = info.Severity
+    return result
+}
+
+// resultAllowed 判断结果是否通过 --include、--exclude、--min-confidence 和 --suppress 筛选
+func resultAllowed(result config.Result) bool {
+    if (suppressSoft404 && result.SoftNotFound) || (suppressSoft404Host && result.SoftNotFoundHost) || (suppressHoneypot && result.Honeypot) {
+        return false
+    }
+    if result.Confidence < minConfidence {
+        return false
+    }
+    if len(includeFilters) > 0 && !matchesFilter(result, includeFilters) {
+        return false
+    }
+    return !matchesFilter(result, excludeFilters)
+}
+
+// matchesFilter 判断结果的类别或任一标签是否在筛选值中
+func matchesFilter(result config.Result, filters []string) bool {
+    for _, filter := range filters {
+        if result.Category == filter {
+            return true
+        }
+        for _, tag := range result.Tags {
+            if tag == filter {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// filterResults 返回通过筛选的结果
+func filterResults(results []config.Result) []config.Result {
+    var allowed []config.Result
+    for _, result := range results {
+        if resultAllowed(result) {
+            allowed = append(allowed, result)
+        }
+    }
+    return allowed
+}
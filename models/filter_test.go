@@ -0,0 +1,68 @@
+package models
+
+import (
+    "reflect"
+    "testing"
+
+    "hfinger/config"
+)
+
+// TestFilterResults --include 只保留类别或标签匹配的结果，--exclude 优先于 --include，均忽略大小写
+func TestFilterResults(t *testing.T) {
+    t.Cleanup(func() { SetResultFilter(nil, nil) })
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"], "category": "Web Server"},
+        {"cms": "Shiro", "method": "keyword", "location": "header", "logic": "or", "rule": ["Set-Cookie: rememberMe"], "category": "framework", "tags": ["Java", " RCE "]},
+        {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa"], "category": "oa", "tags": ["java"]},
+        {"cms": "Unknown", "method": "keyword", "location": "body", "logic": "or", "rule": ["unknown-app"]}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var results []config.Result
+    for _, cms := range []string{"Nginx", "Shiro", "DemoOA", "Unknown"} {
+        results = append(results, withProductInfo(config.Result{CMS: cms}, fingerConfig))
+    }
+
+    tests := []struct {
+        name    string
+        include []string
+        exclude []string
+        want    []string
+    }{
+        {"no filter", nil, nil, []string{"Nginx", "Shiro", "DemoOA", "Unknown"}},
+        {"include category", []string{"web server"}, nil, []string{"Nginx"}},
+        {"include tag", []string{" JAVA "}, nil, []string{"Shiro", "DemoOA"}},
+        {"include category or tag", []string{"oa", "rce"}, nil, []string{"Shiro", "DemoOA"}},
+        {"exclude tag", nil, []string{"java"}, []string{"Nginx", "Unknown"}},
+        {"exclude wins over include", []string{"java"}, []string{"RCE"}, []string{"DemoOA"}},
+        {"blank values are ignored", []string{"", " "}, []string{""}, []string{"Nginx", "Shiro", "DemoOA", "Unknown"}},
+        {"include matches nothing", []string{"database"}, nil, nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            SetResultFilter(tt.include, tt.exclude)
+            var got []string
+            for _, result := range filterResults(results) {
+                got = append(got, result.CMS)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("results = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestMinConfidence 可信度低于 --min-confidence 的结果不输出
+func TestMinConfidence(t *testing.T) {
+    t.Cleanup(func() { SetMinConfidence(0) })
+    results := []config.Result{{CMS: "Nginx", Confidence: 100}, {CMS: "Shiro", Confidence: 60}, {CMS: "DemoOA", Confidence: 30}}
+    SetMinConfidence(60)
+    var got []string
+    for _, result := range filterResults(results) {
+        got = append(got, result.CMS)
+    }
+    if want := []string{"Nginx", "Shiro"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("results = %q, want %q", got, want)
+    }
+}
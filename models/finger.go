@@ -1,10 +1,13 @@
 package models
 
 import (
+    "bufio"
+    "bytes"
     "fmt"
     "io"
     "os"
     "net/http"
+    neturl "net/url"
     "strings"
     "sync"
     "math/rand"
@@ -19,9 +22,21 @@ var (
     workerCount int
     maxRedirects int
     outputLock sync.Mutex // 全局锁保护output操作
+    probeSem chan struct{} // 限制所有目标的主动探测请求并发数
+    assetsEnabled bool     // 是否下载页面引用的脚本和样式表
+    assetsMaxCount int     // 每个目标最多下载的脚本和样式表数量
+    assetsMaxSize int64    // 单个脚本或样式表的最大字节数，超过则跳过
 )
 
-func process(url string, headers map[string]string, resultsChannel chan<- config.Result, matchedCMS *sync.Map, mu *sync.Mutex, wg *sync.WaitGroup, errOccurred *bool, saveResponse func(int, string, string)) {
+// maxTargetLineSize 为目标文件单行的最大字节数
+const maxTargetLineSize = 1024 * 1024
+
+// maxResourceSize 为图标、manifest 和 filehash 静态资源的最大字节数，超过则跳过
+const maxResourceSize = 4 * 1024 * 1024
+
+// process 请求目标并匹配指纹，fingers 为参与匹配的指纹在 fingerConfig 中的下标，为 nil 时使用默认请求的指纹
+// page 不为 nil 时保存最终响应的摘要，用于软404检测
+func process(fingerConfig *config.FingerprintConfig, url string, method string, headers map[string]string, fingers []int, cache *assetCache, resultsChannel chan<- config.Result, mu *sync.Mutex, wg *sync.WaitGroup, errOccurred *bool, saveResponse func(int, string, string), page *pageSignature) {
     defer wg.Done()
     
     currentURL := url
@@ -35,7 +50,7 @@ func process(url string, headers map[string]string, resultsChannel chan<- config
         }
         mu.Unlock()
         
-        resp, err := utils.Get(currentURL, headers)
+        resp, err := utils.Request(method, currentURL, headers)
         if err != nil {
             mu.Lock()
             if !*errOccurred {
@@ -75,66 +90,283 @@ func process(url string, headers map[string]string, resultsChannel chan<- config
         if server == "" {
             server = "None"
         }
-        title := utils.FetchTitle(body)
+        // 标题提取和关键词匹配使用转码为 UTF-8 的内容，GBK、Big5 页面的中文规则才能命中
+        text := utils.DecodeToUTF8(body, resp.Header.Get("Content-Type"))
+        title := utils.FetchTitle(text)
         if title == "" {
             title = "None"
         }
 
-        faviconpath := utils.FetchFavicon(body)
-        var faviconbody []byte
-        if faviconpath != "" && resp.StatusCode == http.StatusOK {
-            baseurl, _ := utils.GetBaseURL(currentURL)
-            faviconurl := faviconpath
-            if !strings.HasPrefix(faviconpath, "http://") && !strings.HasPrefix(faviconpath, "https://") {
-                if faviconpath[0] == '/' {
-                    faviconurl = baseurl + faviconpath
-                } else {
-                    faviconurl = baseurl + "/" + faviconpath
-                }
-            }
-            
-            favicon, err := utils.Get(faviconurl, nil)
-            if err == nil && favicon.StatusCode == http.StatusOK {
-                defer favicon.Body.Close()
-                faviconbody, err = io.ReadAll(favicon.Body)
-                if err != nil {
-                    logger.PrintByLevel(err, currentURL)
-                }
-            }
+        certInfo := utils.PeerCertificate(resp)
+        cert := certInfo.Text()
+
+        baseurl, _ := utils.GetBaseURL(currentURL)
+        var icons []iconInfo
+        if resp.StatusCode == http.StatusOK {
+            icons = fetchIcons(currentURL, text, cache)
+        }
+
+        // 指纹匹配
+        products := matchConfigProducts(fingerConfig, text, resp.Header, title, cert, icons, fingers)
+
+        if page != nil {
+            mu.Lock()
+            *page = newPageSignature(currentURL, statusCode, text)
+            mu.Unlock()
         }
 
         // 保存第一次请求结果，无匹配结果时输出
         if saveResponse != nil {
             saveResponse(statusCode, server, title)
-        }
-
-        // 指纹匹配
-        for _, fingerprint := range config.Config.Finger {
-            ismatched := matchKeywords(body, resp.Header, title, faviconbody, fingerprint)
-            if ismatched {
-                cms := fingerprint.CMS
-                if _, loaded := matchedCMS.LoadOrStore(cms, true); !loaded {
-                    result := config.Result{
-                        URL:        currentURL, // 使用当前URL（可能是重定向后的）
-                        CMS:        cms,
-                        Server:     server,
-                        StatusCode: statusCode,
-                        Title:      title,
-                    }
-                    resultsChannel <- result
-                    logger.Success("[%s] [%s] [%d] [%s] [%s]", currentURL, cms, statusCode, server, title)
+            // 仅随首页请求下载 filehash 指纹声明的静态资源
+            for _, asset := range fingerConfig.Assets {
+                if assetbody := cache.fetch(baseurl + asset.Path, maxResourceSize); assetbody != nil {
+                    products = mergeProducts(products, matchConfigProducts(fingerConfig, assetbody, nil, "", "", nil, asset.Fingers))
                 }
             }
+            products = mergeProducts(products, matchLinkedAssets(fingerConfig, currentURL, text, cache))
+        }
+
+        // 同一目标的多个请求可能命中同一产品，由 ProcessURL 统一去重并输出
+        for _, product := range products {
+            result := withCert(config.Result{
+                URL:        currentURL, // 使用当前URL（可能是重定向后的）
+                CMS:        product.CMS,
+                Version:    product.Version,
+                Server:     server,
+                StatusCode: statusCode,
+                Title:      title,
+                IconURL:    product.IconURL,
+                IconHash:   product.IconHash,
+                Confidence: product.Confidence,
+            }, certInfo)
+            resultsChannel <- withProductInfo(withEvidence(result, product.Evidence), fingerConfig)
         }
         break // 退出循环
     }
 }
 
+// withCert 将目标的证书信息写入结果，非 HTTPS 目标保持为空
+func withCert(result config.Result, info *utils.CertInfo) config.Result {
+    if info != nil {
+        result.CertSubject = info.Subject
+        result.CertIssuer = info.Issuer
+        result.CertSANs = info.SANs
+        result.CertSerial = info.Serial
+        result.CertSHA256 = info.SHA256
+        result.GMTLS = info.GMTLS
+    }
+    return result
+}
+
+// matchedProduct 记录单次响应中命中的产品、提取到的版本以及命中的图标
+type matchedProduct struct {
+    CMS        string
+    Version    string
+    IconURL    string
+    IconHash   string
+    Evidence   matchEvidence
+    Confidence int
+}
+
+// matchProducts 使用当前指纹库中指定的指纹匹配一次响应，同一产品只返回一次，版本取第一个提取成功的指纹
+// fingers 为 nil 时使用不需要主动探测的全部指纹
+func matchProducts(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingers []int) []matchedProduct {
+    return matchConfigProducts(currentFingerprints(), body, header, title, cert, icons, fingers)
+}
+
+// matchConfigProducts 使用指定的指纹库进行匹配，fingers 为该指纹库中的下标
+func matchConfigProducts(fingerConfig *config.FingerprintConfig, body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingers []int) []matchedProduct {
+    var products []matchedProduct
+    index := make(map[string]int)
+    if fingers == nil {
+        fingers = fingerConfig.RootFingers
+    }
+    // 关键词规则通过自动机对每个位置只扫描一次，参与匹配的指纹都未建立索引时跳过扫描
+    var hits config.KeywordHits
+    if body != nil && fingerConfig.Index != nil {
+        for _, i := range fingers {
+            if fingerConfig.Index.RuleIDs(i) != nil {
+                hits = fingerConfig.Index.Scan(body, header, title)
+                break
+            }
+        }
+    }
+    for _, i := range fingers {
+        fingerprint := fingerConfig.Finger[i]
+        var matched bool
+        if ruleIDs := fingerConfig.Index.RuleIDs(i); ruleIDs != nil {
+            matched = body != nil && matchIndexed(ruleIDs, hits[fingerprint.Location], fingerprint.Logic) &&
+                !matchNot(body, header, title, cert, fingerprint)
+        } else {
+            matched = matchKeywords(body, header, title, cert, icons, fingerprint)
+        }
+        if !matched {
+            continue
+        }
+        product := matchedProduct{
+            CMS:        fingerprint.CMS,
+            Version:    extractVersion(body, header, title, cert, fingerprint),
+            Evidence:   collectEvidence(body, header, title, cert, icons, fingerprint),
+            Confidence: config.FingerprintWeight(fingerprint),
+        }
+        if icon, ok := productIcon(icons, fingerprint); ok {
+            product.IconURL, product.IconHash = icon.URL, icon.Hash
+        }
+        if i, exists := index[fingerprint.CMS]; exists {
+            products[i].fill(product)
+            continue
+        }
+        index[fingerprint.CMS] = len(products)
+        products = append(products, product)
+    }
+    return products
+}
+
+// fill 使用同一产品的另一次命中补充缺失的版本和图标，并合并两次命中的可信度
+func (p *matchedProduct) fill(other matchedProduct) {
+    p.Confidence = config.CombineConfidence(p.Confidence, other.Confidence)
+    if p.Version == "" {
+        p.Version = other.Version
+    }
+    if p.IconURL == "" {
+        p.IconURL, p.IconHash = other.IconURL, other.IconHash
+    }
+    if p.Evidence.FingerprintID == "" {
+        p.Evidence = other.Evidence
+    }
+}
+
+// mergeProducts 合并两次匹配的结果，已存在的产品只补充缺失的版本和图标
+func mergeProducts(products []matchedProduct, more []matchedProduct) []matchedProduct {
+    for _, product := range more {
+        merged := false
+        for i := range products {
+            if products[i].CMS == product.CMS {
+                products[i].fill(product)
+                merged = true
+                break
+            }
+        }
+        if !merged {
+            products = append(products, product)
+        }
+    }
+    return products
+}
+
+// agreeConfidence 合并同一目标不同请求（首页、随机路径、rememberMe、探测路径）对同一产品的可信度
+// 多个请求都命中时提高可信度，较低的一方按一半计入，避免同一页面的重复命中被高估
+func agreeConfidence(a int, b int) int {
+    if a < b {
+        a, b = b, a
+    }
+    return config.CombineConfidence(a, b/2)
+}
+
+// assetCache 缓存单个目标已下载的图标和静态资源，同一资源只请求一次
+type assetCache struct {
+    entries sync.Map
+}
+
+type assetEntry struct {
+    once sync.Once
+    body []byte
+}
+
+// fetch 下载资源并缓存，请求失败、状态码不为200或超过 limit 字节时返回 nil
+// 同一地址只下载一次，按第一次请求的 limit 判断大小
+func (c *assetCache) fetch(url string, limit int64) []byte {
+    value, _ := c.entries.LoadOrStore(url, &assetEntry{})
+    entry := value.(*assetEntry)
+    entry.once.Do(func() {
+        resp, err := utils.Get(url, nil)
+        if err != nil {
+            return
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+            return
+        }
+        body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+        if err != nil {
+            logger.PrintByLevel(err, url)
+            return
+        }
+        if int64(len(body)) > limit {
+            return
+        }
+        entry.body = body
+    })
+    return entry.body
+}
+
+// matchLinkedAssets 下载页面引用的同源脚本和样式表，并与 location 为 js、css 的指纹进行匹配
+func matchLinkedAssets(fingerConfig *config.FingerprintConfig, pageURL string, body []byte, cache *assetCache) []matchedProduct {
+    if !assetsEnabled || (len(fingerConfig.ScriptFingers) == 0 && len(fingerConfig.StyleFingers) == 0) {
+        return nil
+    }
+    page, err := neturl.Parse(pageURL)
+    if err != nil {
+        return nil
+    }
+
+    scripts, styles := utils.FetchAssetLinks(body)
+    fetched := 0
+    seen := make(map[string]bool)
+    collect := func(links []string) []byte {
+        var content bytes.Buffer
+        for _, link := range links {
+            if fetched >= assetsMaxCount {
+                break
+            }
+            resolved, err := utils.ResolveRelativeURL(pageURL, link)
+            if err != nil || seen[resolved] {
+                continue
+            }
+            assetURL, err := neturl.Parse(resolved)
+            if err != nil || assetURL.Scheme != page.Scheme || assetURL.Host != page.Host {
+                continue
+            }
+            seen[resolved] = true
+            fetched++
+            if data := cache.fetch(resolved, assetsMaxSize); data != nil {
+                content.Write(data)
+                content.WriteByte('\n')
+            }
+        }
+        return content.Bytes()
+    }
+
+    var products []matchedProduct
+    if len(fingerConfig.ScriptFingers) > 0 {
+        if js := collect(scripts); len(js) > 0 {
+            products = mergeProducts(products, matchConfigProducts(fingerConfig, js, nil, "", "", nil, fingerConfig.ScriptFingers))
+        }
+    }
+    if len(fingerConfig.StyleFingers) > 0 {
+        if css := collect(styles); len(css) > 0 {
+            products = mergeProducts(products, matchConfigProducts(fingerConfig, css, nil, "", "", nil, fingerConfig.StyleFingers))
+        }
+    }
+    return products
+}
+
+// productLabel 拼接产品名称和版本用于控制台输出
+func productLabel(cms string, version string) string {
+    if version == "" {
+        return cms
+    }
+    return cms + " " + version
+}
+
 func ProcessURL(url string) {
+    // 同一目标的全部请求使用同一个指纹库，指纹下标和匹配结果保持一致
+    fingerConfig := currentFingerprints()
     var wg sync.WaitGroup
     var mu sync.Mutex
     var errOccurred bool
-    var matchedCMS sync.Map
+    var cache assetCache
     resultsChannel := make(chan config.Result, workerCount)
 
     var lastResp config.LastResponse
@@ -156,35 +388,78 @@ func ProcessURL(url string) {
         })
     }
 
+    // 在请求进行中持续收集结果，避免结果数量超过通道容量时阻塞
+    // 同一产品只保留最先返回的结果，版本从其它请求的结果中补充
+    var results []config.Result
+    matchedURLs := make(map[string][]string) // 每个产品在哪些页面上被识别出
+    collected := make(chan struct{})
+    go func() {
+        index := make(map[string]int)
+        for result := range resultsChannel {
+            matchedURLs[result.CMS] = append(matchedURLs[result.CMS], result.URL)
+            if i, exists := index[result.CMS]; exists {
+                if results[i].Version == "" {
+                    results[i].Version = result.Version
+                }
+                if results[i].IconURL == "" {
+                    results[i].IconURL, results[i].IconHash = result.IconURL, result.IconHash
+                }
+                results[i].Confidence = agreeConfidence(results[i].Confidence, result.Confidence)
+                continue
+            }
+            index[result.CMS] = len(results)
+            results = append(results, result)
+        }
+        close(collected)
+    }()
+
     wg.Add(3)
-    go process(url, nil, resultsChannel, &matchedCMS, &mu, &wg, &errOccurred, saveFirstResponse)
-    go process(url, map[string]string{"Cookie": "rememberMe=1"}, resultsChannel, &matchedCMS, &mu, &wg, &errOccurred, nil)
+    var rootPage, randomPage pageSignature
+    go process(fingerConfig, url, "GET", nil, nil, &cache, resultsChannel, &mu, &wg, &errOccurred, saveFirstResponse, &rootPage)
+    go process(fingerConfig, url, "GET", map[string]string{"Cookie": "rememberMe=1"}, nil, &cache, resultsChannel, &mu, &wg, &errOccurred, nil, nil)
     
     suffix := fmt.Sprintf("/%x", rand.Int())
     if url[len(url)-1] == '/' {
         suffix = fmt.Sprintf("%x", rand.Int())
     }
     newUrl := url + suffix
-    go process(newUrl, nil, resultsChannel, &matchedCMS, &mu, &wg, &errOccurred, nil)
+    go process(fingerConfig, newUrl, "GET", nil, nil, &cache, resultsChannel, &mu, &wg, &errOccurred, nil, &randomPage)
+
+    // 指纹声明的探测路径，每个目标每个路径只请求一次
+    if baseURL, err := utils.GetBaseURL(url); err == nil {
+        for _, probe := range fingerConfig.Probes {
+            wg.Add(1)
+            go func(probe *config.Probe) {
+                probeSem <- struct{}{}
+                defer func() { <-probeSem }()
+                process(fingerConfig, baseURL+probe.Path, probe.Method, probe.Headers, probe.Fingers, &cache, resultsChannel, &mu, &wg, &errOccurred, nil, nil)
+            }(probe)
+        }
+    }
     
     wg.Wait()
 
     close(resultsChannel)
+    <-collected
 
-    var results []config.Result
-    for result := range resultsChannel {
-        results = append(results, result)
+    // 结果在目标的全部请求完成后才输出：软404标记、产品关联、可信度合并和筛选都依赖目标的全部结果，已输出的结果无法再标记或撤回
+    flagSuspiciousResults(url, results, rootPage, randomPage, matchedURLs)
+    results = resolveRelations(fingerConfig, results)
+    // 被 --include 或 --exclude 过滤掉全部结果的目标不再输出未匹配
+    allowed := filterResults(results)
+    for _, result := range allowed {
+        logger.Success("[%s] [%s] [%d] [%s] [%s]%s%s", result.URL, resultLabel(result), result.StatusCode, result.Server, result.Title, resultFlags(result), evidenceLine(result))
     }
 
     outputLock.Lock()
     defer outputLock.Unlock()
-    for _, result := range results {
+    for _, result := range allowed {
         output.AddResults(result)
     }
 
     mu.Lock()
     defer mu.Unlock()
-    if countItems(&matchedCMS) == 0 && !errOccurred && lastResp.StatusCode != 0 {
+    if len(results) == 0 && !errOccurred && lastResp.StatusCode != 0 {
         logger.Info("[%s] [Not Matched] [%d] [%s] [%s]",
             url,
             lastResp.StatusCode,
@@ -193,63 +468,106 @@ func ProcessURL(url string) {
     }
 }
 
-func countItems(m *sync.Map) int {
-    count := 0
-    m.Range(func(_, _ interface{}) bool {
-        count++
-        return true
-    })
-    return count
+// ProcessSingleTarget 识别 -u 指定的目标，目标与目标文件中的一行一样展开，识别完成后与 -f 一样写入输出文件
+func ProcessSingleTarget(target string) {
+    ProcessTargets(strings.NewReader(target))
+    writeOutputs()
 }
 
+// ProcessFile 逐行读取目标文件并识别，filePath 为 - 时读取标准输入
 func ProcessFile(filePath string) {
-    data, err := os.ReadFile(filePath)
-    if err != nil {
-        logger.Error("Error: %v", err)
-        return
+    input := os.Stdin
+    if filePath != "-" {
+        file, err := os.Open(filePath)
+        if err != nil {
+            logger.Error("Error: %v", err)
+            return
+        }
+        defer file.Close()
+        input = file
     }
+    ProcessTargets(input)
+    writeOutputs()
+}
 
-    fileContent := string(data)
-    fileContent = strings.ReplaceAll(fileContent, "\r\n", "\n")
-
-    urls := strings.Split(fileContent, "\n")
+// writeOutputs 将已收集的结果写入 -j、-x、-s 指定的文件
+func writeOutputs() {
+    outputLock.Lock()
+    defer outputLock.Unlock()
+    if err := output.WriteOutputs(); err != nil {
+        logger.Error("Error writing output: %s", err)
+    }
+}
 
+// ProcessTargets 以流的方式读取目标，每行一个，忽略空行和 # 开头的注释
+// 每行按 parseTarget 展开为一个或多个目标，正在识别的目标数达到线程数时暂停读取和展开，读到第一个目标即开始识别，不会将全部目标读入内存
+func ProcessTargets(input io.Reader) {
     var wg sync.WaitGroup
     var sem = make(chan struct{}, workerCount)
 
-    for _, url := range urls {
-        url = strings.TrimSpace(url)
-        if url == "" {
+    scanner := bufio.NewScanner(input)
+    scanner.Buffer(make([]byte, 0, 64*1024), maxTargetLineSize)
+    for scanner.Scan() {
+        line := parseTargetLine(scanner.Text())
+        if line == "" {
+            continue
+        }
+        spec, err := parseTarget(line)
+        if err != nil {
+            logger.Error("Error: Invalid target %s: %v", line, err)
             continue
         }
 
-        wg.Add(1)
-        sem <- struct{}{}
-        go func(u string) {
-            defer wg.Done()
-            defer func() { <-sem }()
-            ProcessURL(u)
-        }(url)
+        spec.each(func(target scanTarget) {
+            wg.Add(1)
+            sem <- struct{}{}
+            go func() {
+                defer wg.Done()
+                defer func() { <-sem }()
+                processScanTarget(target)
+            }()
+        })
+    }
+    if err := scanner.Err(); err != nil {
+        logger.Error("Error: Failed to read targets: %v", err)
     }
 
     wg.Wait()
     close(sem)
+}
 
-    outputLock.Lock()
-    defer outputLock.Unlock()
-    if err := output.WriteOutputs(); err != nil {
-        logger.Error("Error writing output: %s", err)
+// parseTargetLine 去掉行首的 BOM、首尾空白和注释，整行注释以 # 开头，行内注释以空白加 # 开头
+func parseTargetLine(line string) string {
+    line = strings.TrimPrefix(line, "\ufeff")
+    if i := strings.Index(line, " #"); i >= 0 {
+        line = line[:i]
+    }
+    if i := strings.Index(line, "\t#"); i >= 0 {
+        line = line[:i]
     }
+    line = strings.TrimSpace(line)
+    if strings.HasPrefix(line, "#") {
+        return ""
+    }
+    return line
 }
 
 func SetThread(thread int) {
     workerCount = thread
+    probeSem = make(chan struct{}, thread)
 }
 
 func SetMaxRedirects(count int) {
     maxRedirects = count
 }
 
+// SetAssetOptions 设置是否下载页面引用的同源脚本和样式表，以及数量和单个文件大小（KB）的上限
+func SetAssetOptions(enabled bool, maxCount int, maxSizeKB int) {
+    assetsEnabled = enabled
+    assetsMaxCount = maxCount
+    assetsMaxSize = int64(maxSizeKB) * 1024
+}
+
 func ShowFingerPrints() {
     fingerprints := config.Config
     fingerCount := len(fingerprints.Finger)
@@ -263,4 +581,20 @@ func ShowFingerPrints() {
         }
     }
     logger.Hint("Total number of products, web frameworks, and CMS: %d", uniqueCount)
+    // 只有官方指纹库时与总数相同，不再单独输出
+    if len(fingerprints.Sources) > 1 {
+        for _, source := range fingerprints.Sources {
+            line := fmt.Sprintf("Fingerprints from %s: %d", source.Path, source.Count)
+            if source.Overridden > 0 {
+                line += fmt.Sprintf(", %d overridden", source.Overridden)
+            }
+            if source.Disabled > 0 {
+                line += fmt.Sprintf(", %d disabled", source.Disabled)
+            }
+            logger.Hint("%s", line)
+        }
+    }
+    if len(fingerprints.Banner) > 0 {
+        logger.Hint("Total number of TCP banner fingerprints: %d", len(fingerprints.Banner))
+    }
 }
\ No newline at end of file
@@ -0,0 +1,287 @@
+package models
+
+import (
+    "bytes"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "hfinger/config"
+    "hfinger/output"
+    "hfinger/utils"
+)
+
+// TestAssetCacheFetch 超过大小上限或状态码不为200的资源返回 nil，同一地址只请求一次
+func TestAssetCacheFetch(t *testing.T) {
+    requests := make(map[string]int)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests[r.URL.Path]++
+        switch r.URL.Path {
+        case "/small.js":
+            w.Write(bytes.Repeat([]byte("a"), 16))
+        case "/large.js":
+            w.Write(bytes.Repeat([]byte("a"), 17))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer server.Close()
+    if err := utils.InitializeHTTPClient("", 5*time.Second, 3); err != nil {
+        t.Fatal(err)
+    }
+
+    var cache assetCache
+    tests := []struct {
+        path string
+        size int
+    }{
+        {"/small.js", 16},
+        {"/large.js", 0},
+        {"/missing.js", 0},
+        {"/small.js", 16},
+    }
+    for _, tt := range tests {
+        if data := cache.fetch(server.URL+tt.path, 16); len(data) != tt.size {
+            t.Errorf("fetch(%s) returned %d bytes, want %d", tt.path, len(data), tt.size)
+        }
+    }
+    if requests["/small.js"] != 1 {
+        t.Errorf("/small.js requested %d times, want 1", requests["/small.js"])
+    }
+}
+
+// TestMatchLinkedAssets 只下载同源的脚本和样式表，下载数量受 --assets-max-count 限制，超过 --assets-max-size 的文件被跳过
+func TestMatchLinkedAssets(t *testing.T) {
+    var mu sync.Mutex
+    requests := make(map[string]int)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        requests[r.URL.Path]++
+        mu.Unlock()
+        switch r.URL.Path {
+        case "/static/a.js":
+            w.Write([]byte("var demo = 'demo-a';"))
+        case "/static/big.js":
+            w.Write([]byte("demo-big " + strings.Repeat("x", 2048)))
+        case "/static/b.js":
+            w.Write([]byte("var demo = 'demo-b';"))
+        case "/static/c.js":
+            w.Write([]byte("var demo = 'demo-c';"))
+        case "/static/site.css":
+            w.Write([]byte(".demo-css { color: red; }"))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer server.Close()
+    if err := utils.InitializeHTTPClient("", 5*time.Second, 3); err != nil {
+        t.Fatal(err)
+    }
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "DemoA", "method": "keyword", "location": "js", "logic": "or", "rule": ["demo-a"]},
+        {"cms": "DemoBig", "method": "keyword", "location": "js", "logic": "or", "rule": ["demo-big"]},
+        {"cms": "DemoB", "method": "keyword", "location": "js", "logic": "or", "rule": ["demo-b"]},
+        {"cms": "DemoC", "method": "keyword", "location": "js", "logic": "or", "rule": ["demo-c"]},
+        {"cms": "DemoCSS", "method": "keyword", "location": "css", "logic": "or", "rule": [".demo-css"]}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    body := []byte(`<html><head>
+        <script src="/static/a.js"></script>
+        <script src="/static/a.js"></script>
+        <script src="http://cdn.example.com/static/x.js"></script>
+        <script src="/static/big.js"></script>
+        <script src="static/b.js"></script>
+        <script src="/static/c.js"></script>
+        <link rel="stylesheet" href="/static/site.css">
+    </head></html>`)
+
+    enabled, maxCount, maxSize := assetsEnabled, assetsMaxCount, assetsMaxSize
+    t.Cleanup(func() { assetsEnabled, assetsMaxCount, assetsMaxSize = enabled, maxCount, maxSize })
+    tests := []struct {
+        name      string
+        enabled   bool
+        maxCount  int
+        maxSizeKB int
+        want      string
+        fetched   int
+    }{
+        {"disabled", false, 10, 4, "", 0},
+        {"count and size limits", true, 3, 1, "DemoA, DemoB", 3},
+        {"no limits reached", true, 10, 4, "DemoA, DemoBig, DemoB, DemoC, DemoCSS", 5},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            SetAssetOptions(tt.enabled, tt.maxCount, tt.maxSizeKB)
+            mu.Lock()
+            requests = make(map[string]int)
+            mu.Unlock()
+            products := matchLinkedAssets(fingerConfig, server.URL+"/", body, &assetCache{})
+            var names []string
+            for _, product := range products {
+                names = append(names, product.CMS)
+            }
+            if got := strings.Join(names, ", "); got != tt.want {
+                t.Errorf("products = %q, want %q", got, tt.want)
+            }
+            mu.Lock()
+            defer mu.Unlock()
+            if len(requests) != tt.fetched {
+                t.Errorf("requested %v, want %d files", requests, tt.fetched)
+            }
+            for path, count := range requests {
+                if count != 1 {
+                    t.Errorf("%s requested %d times, want 1", path, count)
+                }
+            }
+        })
+    }
+}
+
+// TestMatchConfidence 同一响应中同一产品的多条指纹命中时合并可信度，不同请求都命中时较低的一方按一半计入
+func TestMatchConfidence(t *testing.T) {
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]},
+        {"cms": "Nginx", "method": "keyword", "location": "body", "logic": "or", "rule": ["Welcome to nginx"]},
+        {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa"], "weight": 95}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    header := map[string][]string{"Server": {"nginx/1.24.0"}}
+    tests := []struct {
+        name string
+        body string
+        want map[string]int
+    }{
+        {"header only", "<html></html>", map[string]int{"Nginx": 50}},
+        {"header and body", "<h1>Welcome to nginx!</h1>", map[string]int{"Nginx": 70}},
+        {"explicit weight", "<div>demo-oa</div>", map[string]int{"Nginx": 50, "DemoOA": 95}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := make(map[string]int)
+            for _, product := range matchConfigProducts(fingerConfig, []byte(tt.body), header, "", "", nil, nil) {
+                got[product.CMS] = product.Confidence
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("confidence = %v, want %v", got, tt.want)
+            }
+        })
+    }
+
+    for _, tt := range []struct{ a, b, want int }{{50, 50, 63}, {40, 80, 84}, {80, 40, 84}, {90, 1, 90}} {
+        if got := agreeConfidence(tt.a, tt.b); got != tt.want {
+            t.Errorf("agreeConfidence(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+// startDemoServer 启动返回 DemoOA 页面的服务，每次请求首页时向 requested 发送通知
+func startDemoServer(t *testing.T, requested chan<- string) *httptest.Server {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/" && r.Header.Get("Cookie") == "" {
+            select {
+            case requested <- r.Host:
+            default:
+            }
+        }
+        w.Write([]byte("<html><title>Demo</title><div>demo-oa</div></html>"))
+    }))
+    t.Cleanup(server.Close)
+    return server
+}
+
+// setDemoLibrary 使用只包含 DemoOA 的指纹库和一个识别线程，测试结束后恢复
+func setDemoLibrary(t *testing.T) {
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa"]}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    previousConfig, previousThread := config.Config, workerCount
+    t.Cleanup(func() {
+        config.Config = previousConfig
+        SetThread(previousThread)
+    })
+    config.Config = fingerConfig
+    SetThread(1)
+    setTargetOptions(t, "", "auto", false)
+    if err := utils.InitializeHTTPClient("", 5*time.Second, 3); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// resultURLs 返回 output 中已收集的 CMS 为 cms 的结果的 URL
+func resultURLs(cms string) map[string]bool {
+    urls := make(map[string]bool)
+    for _, result := range output.GetResults() {
+        if result.CMS == cms {
+            urls[result.URL] = true
+        }
+    }
+    return urls
+}
+
+// TestProcessTargets 读到第一行目标即开始识别，不等待输入结束，跳过空行和注释
+func TestProcessTargets(t *testing.T) {
+    setDemoLibrary(t)
+    requested := make(chan string, 1)
+    first, second := startDemoServer(t, requested), startDemoServer(t, requested)
+
+    reader, writer := io.Pipe()
+    done := make(chan struct{})
+    go func() {
+        ProcessTargets(reader)
+        close(done)
+    }()
+    io.WriteString(writer, "\ufeff# targets\n"+first.URL+"\n")
+    select {
+    case host := <-requested:
+        if host != first.Listener.Addr().String() {
+            t.Errorf("requested %s first, want %s", host, first.Listener.Addr())
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("first target was not requested before the input ended")
+    }
+    io.WriteString(writer, "\n   \n"+second.URL+" # second server\n")
+    writer.Close()
+    select {
+    case <-done:
+    case <-time.After(10 * time.Second):
+        t.Fatal("ProcessTargets did not return after the input ended")
+    }
+
+    urls := resultURLs("DemoOA")
+    if !urls[first.URL] || !urls[second.URL] {
+        t.Errorf("results for %v, want %s and %s", urls, first.URL, second.URL)
+    }
+}
+
+// TestProcessSingleTarget -u 指定的目标识别完成后与 -f 一样写入输出文件
+func TestProcessSingleTarget(t *testing.T) {
+    setDemoLibrary(t)
+    server := startDemoServer(t, nil)
+    path := filepath.Join(t.TempDir(), "result.json")
+    if err := output.SetOutput("json", path); err != nil {
+        t.Fatal(err)
+    }
+
+    ProcessSingleTarget(server.URL)
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("output file not written: %v", err)
+    }
+    if !strings.Contains(string(data), server.URL) || !strings.Contains(string(data), "DemoOA") {
+        t.Errorf("output file does not contain the result for %s: %s", server.URL, data)
+    }
+}
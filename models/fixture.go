@@ -0,0 +1,230 @@
+package models
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    neturl "net/url"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "unicode/utf8"
+
+    "hfinger/config"
+    "hfinger/logger"
+    "hfinger/utils"
+)
+
+// Fixture 为录制的一次首页响应和预期识别出的产品，用于离线回归测试指纹库
+type Fixture struct {
+    URL      string              `json:"url"`
+    Status   int                 `json:"status"`
+    Header   map[string][]string `json:"header"`
+    Body     string              `json:"body"`
+    RawBody  []byte              `json:"raw_body,omitempty"` // 不是有效 UTF-8 的响应体（如 GBK 页面）以 base64 保存，设置后忽略 Body
+    Cert     string              `json:"cert,omitempty"`  // HTTPS 目标的证书文本，与 location 为 cert 的规则匹配
+    Icons    []FixtureIcon       `json:"icons,omitempty"` // 页面声明的图标和 /favicon.ico 的原始内容
+    Expected []string            `json:"expected"`
+}
+
+// FixtureIcon 为录制的图标，Data 在 JSON 中以 base64 保存
+type FixtureIcon struct {
+    URL  string `json:"url"`
+    Data []byte `json:"data"`
+}
+
+var fixtureNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// matchFixture 按在线识别首页响应时的方式匹配录制的响应，返回识别出的产品名称
+func matchFixture(fixture Fixture) []string {
+    body := []byte(fixture.Body)
+    if fixture.RawBody != nil {
+        body = fixture.RawBody
+    }
+    body = utils.DecodeToUTF8(body, http.Header(fixture.Header).Get("Content-Type"))
+    title := utils.FetchTitle(body)
+    if title == "" {
+        title = "None"
+    }
+    var icons []iconInfo
+    for _, icon := range fixture.Icons {
+        if len(icon.Data) > 0 {
+            icons = append(icons, iconInfo{URL: icon.URL, Hash: mmh3Hash32(icon.Data)})
+        }
+    }
+    fingerConfig := currentFingerprints()
+    var results []config.Result
+    for _, product := range matchConfigProducts(fingerConfig, body, fixture.Header, title, fixture.Cert, icons, nil) {
+        results = append(results, config.Result{CMS: product.CMS})
+    }
+    // 与在线识别一致，预期结果包含推导出的产品，不包含被排除的产品
+    var names []string
+    for _, result := range resolveRelations(fingerConfig, results) {
+        names = append(names, result.CMS)
+    }
+    return names
+}
+
+// diffProducts 返回 expected 中未识别出的产品和 actual 中不在预期内的产品
+func diffProducts(expected []string, actual []string) ([]string, []string) {
+    expectedSet := make(map[string]bool, len(expected))
+    for _, name := range expected {
+        expectedSet[name] = true
+    }
+    actualSet := make(map[string]bool, len(actual))
+    var unexpected []string
+    for _, name := range actual {
+        actualSet[name] = true
+        if !expectedSet[name] {
+            unexpected = append(unexpected, name)
+        }
+    }
+    var missing []string
+    for _, name := range expected {
+        if !actualSet[name] {
+            missing = append(missing, name)
+        }
+    }
+    return missing, unexpected
+}
+
+// RunFixtures 离线匹配 dir 目录下全部 .json 录制文件，有遗漏或多余的识别结果时返回 false
+func RunFixtures(dir string) (bool, error) {
+    files, err := readInputFiles(dir, ".json")
+    if err != nil {
+        return false, err
+    }
+    if len(files) == 0 {
+        return false, fmt.Errorf("no fixtures found in %s", dir)
+    }
+
+    passed, failed := 0, 0
+    for _, path := range sortedKeys(files) {
+        name, _ := filepath.Rel(dir, path)
+        if name == "" || name == "." {
+            name = filepath.Base(path)
+        }
+        var fixture Fixture
+        if err := json.Unmarshal(files[path], &fixture); err != nil {
+            logger.Error("[%s] [FAIL] invalid fixture: %v", name, err)
+            failed++
+            continue
+        }
+        actual := matchFixture(fixture)
+        missing, unexpected := diffProducts(fixture.Expected, actual)
+        if len(missing) == 0 && len(unexpected) == 0 {
+            logger.Success("[%s] [PASS] [%s]", name, joinProducts(actual))
+            passed++
+            continue
+        }
+        if len(missing) > 0 {
+            logger.Error("[%s] [FAIL] missing: %s", name, strings.Join(missing, ", "))
+        }
+        if len(unexpected) > 0 {
+            logger.Error("[%s] [FAIL] unexpected: %s", name, strings.Join(unexpected, ", "))
+        }
+        failed++
+    }
+    logger.Hint("Ran %d fixtures in %s, %d passed, %d failed", passed+failed, dir, passed, failed)
+    return failed == 0, nil
+}
+
+func joinProducts(names []string) string {
+    if len(names) == 0 {
+        return "None"
+    }
+    return strings.Join(names, ", ")
+}
+
+// RecordFixture 请求目标首页（跟随重定向），录制响应、证书和图标，并将当前指纹库离线识别出的产品作为预期结果写入 dir 目录
+func RecordFixture(target string, dir string) error {
+    currentURL := target
+    var resp *http.Response
+    var body []byte
+    for redirectCount := 0; ; redirectCount++ {
+        var err error
+        resp, err = utils.Request("GET", currentURL, nil)
+        if err != nil {
+            return err
+        }
+        body, err = io.ReadAll(resp.Body)
+        resp.Body.Close()
+        if err != nil {
+            return err
+        }
+        redirectURL := utils.ExtractRedirectURL(resp, body)
+        if redirectURL == "" || redirectCount >= maxRedirects {
+            break
+        }
+        newURL, err := utils.ResolveRelativeURL(currentURL, redirectURL)
+        if err != nil {
+            logger.Warn("Invalid redirect URL: %s", redirectURL)
+            break
+        }
+        logger.Hint("Redirecting: %s ➨ %s", currentURL, newURL)
+        currentURL = newURL
+    }
+
+    fixture := Fixture{
+        URL:    currentURL,
+        Status: resp.StatusCode,
+        Header: resp.Header,
+        Cert:   utils.PeerCertificate(resp).Text(),
+    }
+    // JSON 字符串无法保存非 UTF-8 字节，这类响应体按原始字节保存，回放时与在线识别一样转码
+    if utf8.Valid(body) {
+        fixture.Body = string(body)
+    } else {
+        fixture.RawBody = body
+    }
+    if resp.StatusCode == http.StatusOK {
+        var cache assetCache
+        for _, icon := range fetchIcons(currentURL, utils.DecodeToUTF8(body, resp.Header.Get("Content-Type")), &cache) {
+            // 图标已由 fetchIcons 下载并缓存，这里只取回原始内容
+            var data []byte
+            if strings.HasPrefix(icon.URL, "data:") {
+                data = decodeDataIcon(icon.URL)
+            } else {
+                data = cache.fetch(icon.URL, maxResourceSize)
+            }
+            fixture.Icons = append(fixture.Icons, FixtureIcon{URL: icon.URL, Data: data})
+        }
+    }
+    fixture.Expected = matchFixture(fixture)
+    if fixture.Expected == nil {
+        fixture.Expected = []string{}
+    }
+
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    path := filepath.Join(dir, fixtureName(currentURL)+".json")
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    encoder.SetEscapeHTML(false)
+    encoder.SetIndent("", "    ")
+    if err := encoder.Encode(fixture); err != nil {
+        return err
+    }
+    if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+        return err
+    }
+    logger.Success("[%s] [%s] Recorded to %s", currentURL, joinProducts(fixture.Expected), path)
+    return nil
+}
+
+// fixtureName 根据目标的主机、端口和路径生成录制文件名
+func fixtureName(target string) string {
+    name := target
+    if u, err := neturl.Parse(target); err == nil && u.Host != "" {
+        name = u.Host + strings.TrimSuffix(u.Path, "/")
+    }
+    name = strings.Trim(fixtureNameReplacer.ReplaceAllString(name, "_"), "_.")
+    if name == "" {
+        name = "fixture"
+    }
+    return name
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "golang.org/x/text/encoding/simplifiedchinese"
+
+    "hfinger/config"
+)
+
+// testFixtureIcon 为录制文件中的图标内容，testFixtureFingers 中的 faviconhash 规则与其哈希对应
+var testFixtureIcon = []byte("\x00\x00\x01\x00demo-icon")
+
+var testFixtureFingers = fmt.Sprintf(`{"finger": [
+    {"cms": "DemoOA", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-oa"], "implies": ["Tomcat"]},
+    {"cms": "Tomcat", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: Apache-Coyote"]},
+    {"cms": "GBK Portal", "method": "keyword", "location": "title", "logic": "or", "rule": ["统一门户"]},
+    {"cms": "DemoIcon", "method": "faviconhash", "location": "body", "logic": "or", "rule": [%q]},
+    {"cms": "DemoCert", "method": "keyword", "location": "cert", "logic": "or", "rule": ["O=Demo Corp"]}
+]}`, mmh3Hash32(testFixtureIcon))
+
+// setFixtureLibrary 使用测试指纹库替换 config.Config，测试结束后恢复
+func setFixtureLibrary(t *testing.T) {
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(testFixtureFingers))
+    if err != nil {
+        t.Fatal(err)
+    }
+    previous := config.Config
+    config.Config = fingerConfig
+    t.Cleanup(func() { config.Config = previous })
+}
+
+// writeFixture 将录制文件写入 dir，返回文件路径
+func writeFixture(t *testing.T, dir string, name string, fixture Fixture) string {
+    data, err := json.Marshal(fixture)
+    if err != nil {
+        t.Fatal(err)
+    }
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, data, 0644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// TestMatchFixture 回放录制的响应时与在线识别一样转码 body、匹配图标和证书并推导关联产品
+func TestMatchFixture(t *testing.T) {
+    setFixtureLibrary(t)
+    gbkPage, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("<html><head><title>统一门户</title></head></html>"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        name    string
+        fixture Fixture
+        want    []string
+    }{
+        {"body and implied product", Fixture{Body: "<div>demo-oa</div>"}, []string{"DemoOA", "Tomcat"}},
+        {"header", Fixture{Header: map[string][]string{"Server": {"Apache-Coyote/1.1"}}, Body: "<html></html>"}, []string{"Tomcat"}},
+        {"gbk raw body", Fixture{Header: map[string][]string{"Content-Type": {"text/html; charset=gbk"}}, RawBody: gbkPage}, []string{"GBK Portal"}},
+        {"icon and cert", Fixture{
+            Body:  "<html></html>",
+            Cert:  "Subject: CN=demo.example.com,O=Demo Corp\nIssuer: CN=Demo CA",
+            Icons: []FixtureIcon{{URL: "http://demo.example.com/favicon.ico", Data: testFixtureIcon}, {URL: "http://demo.example.com/missing.png"}},
+        }, []string{"DemoIcon", "DemoCert"}},
+        {"nothing", Fixture{Body: "<html></html>"}, nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := matchFixture(tt.fixture); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("matched %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestRunFixtures 全部录制文件的结果与预期一致时通过，有遗漏、多余的产品或无效的录制文件时失败
+func TestRunFixtures(t *testing.T) {
+    setFixtureLibrary(t)
+    passing := Fixture{Body: "<div>demo-oa</div>", Expected: []string{"Tomcat", "DemoOA"}}
+    tests := []struct {
+        name   string
+        extra  func(dir string)
+        passed bool
+    }{
+        {"all passed", nil, true},
+        {"missing product", func(dir string) {
+            writeFixture(t, dir, "sub/missing.json", Fixture{Body: "<html></html>", Expected: []string{"DemoOA"}})
+        }, false},
+        {"unexpected product", func(dir string) {
+            writeFixture(t, dir, "unexpected.json", Fixture{Body: "<div>demo-oa</div>", Expected: []string{"DemoOA"}})
+        }, false},
+        {"invalid fixture", func(dir string) {
+            if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+                t.Fatal(err)
+            }
+        }, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            writeFixture(t, dir, "demo.json", passing)
+            if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a fixture"), 0644); err != nil {
+                t.Fatal(err)
+            }
+            if tt.extra != nil {
+                tt.extra(dir)
+            }
+            passed, err := RunFixtures(dir)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if passed != tt.passed {
+                t.Errorf("passed = %v, want %v", passed, tt.passed)
+            }
+        })
+    }
+
+    if _, err := RunFixtures(t.TempDir()); err == nil {
+        t.Error("expected an error for a directory without fixtures")
+    }
+}
@@ -0,0 +1,710 @@
+package models
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "hfinger/config"
+    "hfinger/logger"
+)
+
+// ImportFormats 为 import 命令支持的第三方指纹格式
+var ImportFormats = []string{"ehole", "fingerprinthub", "wappalyzer", "nuclei"}
+
+// importReport 记录转换过程中无法转换或只能部分转换的条目
+type importReport struct {
+    skipped []string
+    partial []string
+}
+
+func (r *importReport) skip(name string, format string, args ...interface{}) {
+    r.skipped = append(r.skipped, fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+func (r *importReport) drop(name string, format string, args ...interface{}) {
+    r.partial = append(r.partial, fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+// ImportFingerprints 将 input（文件或目录）中的第三方指纹转换为 hfinger 指纹，
+// 去除与当前指纹库以及彼此之间重复的条目后写入 outputPath
+func ImportFingerprints(format string, input string, outputPath string) error {
+    var converted []config.Fingerprint
+    report := &importReport{}
+    var err error
+    switch format {
+    case "ehole":
+        converted, err = importEHole(input, report)
+    case "wappalyzer":
+        converted, err = importWappalyzer(input, report)
+    case "fingerprinthub", "nuclei":
+        converted, err = importNuclei(input, format == "nuclei", report)
+    default:
+        return fmt.Errorf("unsupported format %q, expected one of: %s", format, strings.Join(ImportFormats, ", "))
+    }
+    if err != nil {
+        return err
+    }
+
+    // 与当前指纹库按 CMS 名称和规则集合去重
+    seen := make(map[string]bool)
+    if config.Config != nil {
+        for _, fp := range config.Config.Finger {
+            seen[config.FingerprintKey(fp)] = true
+        }
+    }
+    var imported []config.Fingerprint
+    duplicates := 0
+    for _, fp := range converted {
+        if err := config.ValidateFingerprint(fp); err != nil {
+            report.skip(fp.CMS, "%v", err)
+            continue
+        }
+        key := config.FingerprintKey(fp)
+        if seen[key] {
+            duplicates++
+            continue
+        }
+        seen[key] = true
+        imported = append(imported, fp)
+    }
+
+    for _, entry := range report.skipped {
+        logger.Warn("Not converted: %s", entry)
+    }
+    for _, entry := range report.partial {
+        logger.Warn("Partially converted: %s", entry)
+    }
+
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    encoder.SetEscapeHTML(false)
+    encoder.SetIndent("", "    ")
+    if err := encoder.Encode(config.FingerprintConfig{Finger: imported}); err != nil {
+        return err
+    }
+    if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+        return err
+    }
+    logger.Hint("Imported %d fingerprints to %s, %d duplicates, %d not converted, %d partially converted",
+        len(imported), outputPath, duplicates, len(report.skipped), len(report.partial))
+    return nil
+}
+
+// readInputFiles 读取 input 文件，input 为目录时递归读取其中扩展名在 exts 中的文件
+func readInputFiles(input string, exts ...string) (map[string][]byte, error) {
+    info, err := os.Stat(input)
+    if err != nil {
+        return nil, err
+    }
+    files := make(map[string][]byte)
+    if !info.IsDir() {
+        data, err := os.ReadFile(input)
+        if err != nil {
+            return nil, err
+        }
+        files[input] = data
+        return files, nil
+    }
+    err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() {
+            return err
+        }
+        for _, ext := range exts {
+            if strings.EqualFold(filepath.Ext(path), ext) {
+                data, err := os.ReadFile(path)
+                if err != nil {
+                    return err
+                }
+                files[path] = data
+                break
+            }
+        }
+        return nil
+    })
+    return files, err
+}
+
+// sortedKeys 按文件名顺序遍历，保证输出稳定
+func sortedKeys(files map[string][]byte) []string {
+    keys := make([]string, 0, len(files))
+    for key := range files {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// importEHole 转换 EHole 的 finger.json，keyword 中的关键词需要全部出现
+func importEHole(input string, report *importReport) ([]config.Fingerprint, error) {
+    files, err := readInputFiles(input, ".json")
+    if err != nil {
+        return nil, err
+    }
+    var fingers []config.Fingerprint
+    for _, path := range sortedKeys(files) {
+        var ehole struct {
+            Fingerprint []struct {
+                CMS      string   `json:"cms"`
+                Method   string   `json:"method"`
+                Location string   `json:"location"`
+                Keyword  []string `json:"keyword"`
+            } `json:"fingerprint"`
+        }
+        if err := json.Unmarshal(files[path], &ehole); err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        for _, entry := range ehole.Fingerprint {
+            fp := config.Fingerprint{CMS: entry.CMS, Location: entry.Location, Logic: "and", Rule: entry.Keyword}
+            switch entry.Method {
+            case "keyword", "faviconhash":
+                fp.Method = entry.Method
+            case "regula", "regular", "regex":
+                fp.Method = "regex"
+            default:
+                report.skip(entry.CMS, "unsupported method %q", entry.Method)
+                continue
+            }
+            if fp.Method == "faviconhash" {
+                fp.Location = "body"
+            }
+            switch fp.Location {
+            case "body", "header", "title":
+            default:
+                report.skip(entry.CMS, "unsupported location %q", entry.Location)
+                continue
+            }
+            if len(fp.Rule) == 0 {
+                report.skip(entry.CMS, "no keywords")
+                continue
+            }
+            fingers = append(fingers, fp)
+        }
+    }
+    return fingers, nil
+}
+
+// wappalyzerTech 为 Wappalyzer technologies.json 中的单个技术，各字段可能是字符串、数组或对象
+type wappalyzerTech struct {
+    HTML       json.RawMessage `json:"html"`
+    Text       json.RawMessage `json:"text"`
+    ScriptSrc  json.RawMessage `json:"scriptSrc"`
+    Scripts    json.RawMessage `json:"scripts"`
+    Headers    json.RawMessage `json:"headers"`
+    Cookies    json.RawMessage `json:"cookies"`
+    Meta       json.RawMessage `json:"meta"`
+    CertIssuer json.RawMessage `json:"certIssuer"`
+}
+
+// wappalyzerUnsupported 为无法通过单次 HTTP 响应匹配的 Wappalyzer 字段
+var wappalyzerUnsupported = []string{"js", "dom", "css", "url", "xhr", "dns", "robots", "probe"}
+
+// importWappalyzer 转换 Wappalyzer 的 technologies.json 或按字母拆分的 technologies 目录
+// html、scriptSrc 和 meta 转换为 body 正则，headers 和 cookies 转换为 header 正则，certIssuer 转换为 cert 正则
+func importWappalyzer(input string, report *importReport) ([]config.Fingerprint, error) {
+    files, err := readInputFiles(input, ".json")
+    if err != nil {
+        return nil, err
+    }
+    techs := make(map[string]json.RawMessage)
+    for _, path := range sortedKeys(files) {
+        var doc map[string]json.RawMessage
+        if err := json.Unmarshal(files[path], &doc); err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        if nested, ok := doc["technologies"]; ok {
+            doc = nil
+            if err := json.Unmarshal(nested, &doc); err != nil {
+                return nil, fmt.Errorf("%s: %v", path, err)
+            }
+        }
+        for name, raw := range doc {
+            techs[name] = raw
+        }
+    }
+
+    names := make([]string, 0, len(techs))
+    for name := range techs {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var fingers []config.Fingerprint
+    for _, name := range names {
+        var tech wappalyzerTech
+        if err := json.Unmarshal(techs[name], &tech); err != nil {
+            report.skip(name, "%v", err)
+            continue
+        }
+        var fields map[string]json.RawMessage
+        json.Unmarshal(techs[name], &fields)
+
+        body := config.Fingerprint{CMS: name, Method: "regex", Location: "body", Logic: "or"}
+        header := config.Fingerprint{CMS: name, Method: "regex", Location: "header", Logic: "or"}
+        cert := config.Fingerprint{CMS: name, Method: "regex", Location: "cert", Logic: "or"}
+
+        addBody := func(pattern string, wrap func(string) string) {
+            regex, version, ok := parseWappalyzerPattern(pattern)
+            if !ok {
+                report.drop(name, "invalid pattern %q", pattern)
+                return
+            }
+            if wrap != nil {
+                regex = wrap(regex)
+            }
+            regex = "(?i)" + regex
+            body.Rule = append(body.Rule, regex)
+            if version > 0 {
+                body.Version = append(body.Version, config.VersionRule{Location: "body", Regex: regex, Group: version})
+            }
+        }
+        for _, pattern := range wappalyzerStrings(tech.HTML) {
+            addBody(pattern, nil)
+        }
+        for _, pattern := range wappalyzerStrings(tech.Text) {
+            addBody(pattern, nil)
+        }
+        for _, pattern := range append(wappalyzerStrings(tech.ScriptSrc), wappalyzerStrings(tech.Scripts)...) {
+            addBody(pattern, func(regex string) string {
+                return `<script[^>]+src=["']?[^"'>]*` + trimAnchors(regex)
+            })
+        }
+        for _, meta := range wappalyzerPairs(tech.Meta) {
+            addBody(meta[1], func(regex string) string {
+                return `<meta[^>]+name=["']?` + regexp.QuoteMeta(meta[0]) + `["']?[^>]+content=["']?[^"'>]*` + trimAnchors(regex)
+            })
+        }
+
+        for _, pair := range wappalyzerPairs(tech.Headers) {
+            regex, version, ok := parseWappalyzerPattern(pair[1])
+            if !ok {
+                report.drop(name, "invalid header pattern %q", pair[1])
+                continue
+            }
+            header.Rule = append(header.Rule, pair[0]+"~=(?i)"+regex)
+            if version > 0 {
+                header.Version = append(header.Version, config.VersionRule{Location: "header", Header: pair[0], Regex: "(?i)" + regex, Group: version})
+            }
+        }
+        for _, pair := range wappalyzerPairs(tech.Cookies) {
+            regex, _, ok := parseWappalyzerPattern(pair[1])
+            if !ok {
+                report.drop(name, "invalid cookie pattern %q", pair[1])
+                continue
+            }
+            header.Rule = append(header.Rule, "Set-Cookie~=(?i)(?:^|;\\s*)"+regexp.QuoteMeta(pair[0])+"="+trimAnchors(regex))
+        }
+        for _, pattern := range wappalyzerStrings(tech.CertIssuer) {
+            regex, _, ok := parseWappalyzerPattern(pattern)
+            if !ok {
+                report.drop(name, "invalid certIssuer pattern %q", pattern)
+                continue
+            }
+            cert.Rule = append(cert.Rule, "(?i)Issuer: .*"+trimAnchors(regex))
+        }
+
+        var dropped []string
+        for _, field := range wappalyzerUnsupported {
+            if _, ok := fields[field]; ok {
+                dropped = append(dropped, field)
+            }
+        }
+        converted := 0
+        for _, fp := range []config.Fingerprint{body, header, cert} {
+            if len(fp.Rule) > 0 {
+                fingers = append(fingers, fp)
+                converted++
+            }
+        }
+        switch {
+        case converted == 0 && len(dropped) > 0:
+            report.skip(name, "only unsupported fields: %s", strings.Join(dropped, ", "))
+        case converted == 0:
+            report.skip(name, "no patterns")
+        case len(dropped) > 0:
+            report.drop(name, "unsupported fields dropped: %s", strings.Join(dropped, ", "))
+        }
+    }
+    return fingers, nil
+}
+
+// wappalyzerVersion 匹配 Wappalyzer 版本模板中引用的捕获组，例如 \1 或 \1?\1:
+var wappalyzerVersion = regexp.MustCompile(`\\(\d)`)
+
+// parseWappalyzerPattern 拆分 "regex\;version:\1\;confidence:50" 形式的模式，
+// 返回正则（忽略大小写）和版本所在的捕获组，正则无法被 Go 编译时 ok 为 false
+func parseWappalyzerPattern(pattern string) (regex string, group int, ok bool) {
+    parts := strings.Split(pattern, `\;`)
+    regex = parts[0]
+    for _, part := range parts[1:] {
+        if strings.HasPrefix(part, "version:") {
+            if m := wappalyzerVersion.FindStringSubmatch(part); m != nil {
+                group, _ = strconv.Atoi(m[1])
+            }
+        }
+    }
+    re, err := regexp.Compile("(?i)" + regex)
+    if err != nil {
+        return "", 0, false
+    }
+    if group > re.NumSubexp() {
+        group = 0
+    }
+    return regex, group, true
+}
+
+// trimAnchors 去掉首尾的 ^、$ 锚点，以便嵌入到更长的正则中
+func trimAnchors(regex string) string {
+    regex = strings.TrimPrefix(regex, "^")
+    if strings.HasSuffix(regex, "$") && !strings.HasSuffix(regex, `\$`) {
+        regex = strings.TrimSuffix(regex, "$")
+    }
+    return regex
+}
+
+// wappalyzerStrings 解析字符串或字符串数组
+func wappalyzerStrings(raw json.RawMessage) []string {
+    if len(raw) == 0 {
+        return nil
+    }
+    var single string
+    if json.Unmarshal(raw, &single) == nil {
+        return []string{single}
+    }
+    var list []string
+    json.Unmarshal(raw, &list)
+    return list
+}
+
+// wappalyzerPairs 解析名称到模式（字符串或字符串数组）的对象，按名称排序
+func wappalyzerPairs(raw json.RawMessage) [][2]string {
+    if len(raw) == 0 {
+        return nil
+    }
+    var object map[string]json.RawMessage
+    if json.Unmarshal(raw, &object) != nil {
+        return nil
+    }
+    keys := make([]string, 0, len(object))
+    for key := range object {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    var pairs [][2]string
+    for _, key := range keys {
+        patterns := wappalyzerStrings(object[key])
+        if len(patterns) == 0 {
+            patterns = []string{""}
+        }
+        for _, pattern := range patterns {
+            pairs = append(pairs, [2]string{key, pattern})
+        }
+    }
+    return pairs
+}
+
+// nucleiTemplate 为 nuclei 模板以及 FingerprintHub 使用的同格式 YAML
+type nucleiTemplate struct {
+    ID   string `yaml:"id"`
+    Info struct {
+        Name string `yaml:"name"`
+    } `yaml:"info"`
+    HTTP     []nucleiRequest `yaml:"http"`
+    Requests []nucleiRequest `yaml:"requests"`
+}
+
+type nucleiRequest struct {
+    Method            string            `yaml:"method"`
+    Path              []string          `yaml:"path"`
+    Raw               []string          `yaml:"raw"`
+    Headers           map[string]string `yaml:"headers"`
+    Body              string            `yaml:"body"`
+    MatchersCondition string            `yaml:"matchers-condition"`
+    Matchers          []nucleiMatcher   `yaml:"matchers"`
+    Extractors        []nucleiExtractor `yaml:"extractors"`
+}
+
+type nucleiMatcher struct {
+    Type            string   `yaml:"type"`
+    Name            string   `yaml:"name"`
+    Part            string   `yaml:"part"`
+    Condition       string   `yaml:"condition"`
+    Words           []string `yaml:"words"`
+    Regex           []string `yaml:"regex"`
+    Hash            []string `yaml:"hash"`
+    DSL             []string `yaml:"dsl"`
+    Negative        bool     `yaml:"negative"`
+    CaseInsensitive bool     `yaml:"case-insensitive"`
+}
+
+type nucleiExtractor struct {
+    Type  string   `yaml:"type"`
+    Part  string   `yaml:"part"`
+    Regex []string `yaml:"regex"`
+    Group int      `yaml:"group"`
+}
+
+// nucleiFaviconHash 匹配 dsl 中 mmh3(base64_py(body)) 与常量的比较
+var nucleiFaviconHash = []*regexp.Regexp{
+    regexp.MustCompile(`["'](-?\d+)["']\s*==\s*mmh3\(base64_py\(body\)\)`),
+    regexp.MustCompile(`mmh3\(base64_py\(body\)\)\s*==\s*["']?(-?\d+)`),
+}
+
+// importNuclei 转换 nuclei 模板或 FingerprintHub 的 YAML 指纹
+// named 为 true 时按 tech-detect 模板处理：带 name 的 matcher 各自作为一个产品
+func importNuclei(input string, named bool, report *importReport) ([]config.Fingerprint, error) {
+    files, err := readInputFiles(input, ".yaml", ".yml")
+    if err != nil {
+        return nil, err
+    }
+    var fingers []config.Fingerprint
+    for _, path := range sortedKeys(files) {
+        var template nucleiTemplate
+        if err := yaml.Unmarshal(files[path], &template); err != nil {
+            report.skip(path, "%v", err)
+            continue
+        }
+        name := nucleiProductName(template.Info.Name)
+        if name == "" {
+            name = template.ID
+        }
+        requests := append(template.HTTP, template.Requests...)
+        if len(requests) == 0 {
+            report.skip(name, "no http requests")
+            continue
+        }
+        for _, request := range requests {
+            fingers = append(fingers, convertNucleiRequest(name, request, named, report)...)
+        }
+    }
+    return fingers, nil
+}
+
+func convertNucleiRequest(name string, request nucleiRequest, named bool, report *importReport) []config.Fingerprint {
+    if len(request.Raw) > 0 || request.Body != "" {
+        report.skip(name, "raw requests and request bodies are not supported")
+        return nil
+    }
+    var paths []string
+    for _, path := range request.Path {
+        if !strings.HasPrefix(path, "{{BaseURL}}") || strings.Contains(path[len("{{BaseURL}}"):], "{{") {
+            report.skip(name, "unsupported path %q", path)
+            return nil
+        }
+        path = strings.TrimPrefix(path, "{{BaseURL}}")
+        if path == "/" {
+            path = ""
+        }
+        paths = append(paths, path)
+    }
+    if len(paths) == 0 {
+        paths = []string{""}
+    }
+    method := strings.ToUpper(request.Method)
+    if method == "GET" {
+        method = ""
+    }
+
+    var versions []config.VersionRule
+    for _, extractor := range request.Extractors {
+        location, ok := nucleiLocation(extractor.Part)
+        if extractor.Type != "regex" || !ok {
+            continue
+        }
+        for _, regex := range extractor.Regex {
+            if _, err := regexp.Compile(regex); err == nil {
+                versions = append(versions, config.VersionRule{Location: location, Regex: regex, Group: extractor.Group})
+            }
+        }
+    }
+
+    // tech-detect 模板中带 name 的 matcher 各自对应一个产品，其余 matcher 按 matchers-condition 组合
+    groups := make(map[string][]nucleiMatcher)
+    var order []string
+    for _, matcher := range request.Matchers {
+        cms := name
+        if named && matcher.Name != "" {
+            cms = matcher.Name
+        }
+        if _, exists := groups[cms]; !exists {
+            order = append(order, cms)
+        }
+        groups[cms] = append(groups[cms], matcher)
+    }
+    if len(order) == 0 {
+        report.skip(name, "no matchers")
+        return nil
+    }
+
+    condition := "or"
+    if request.MatchersCondition == "and" && !(named && len(order) > 1) {
+        condition = "and"
+    }
+    var fingers []config.Fingerprint
+    for _, cms := range order {
+        fp, ok := convertNucleiMatchers(cms, groups[cms], condition, report)
+        if !ok {
+            continue
+        }
+        fp.Version = versions
+        targets := paths
+        if fp.Method == "faviconhash" {
+            // 图标由首页请求统一收集，不需要单独探测图标路径
+            targets = []string{""}
+        }
+        for _, path := range targets {
+            probe := fp
+            probe.Path = path
+            probe.RequestMethod = method
+            probe.RequestHeaders = request.Headers
+            fingers = append(fingers, probe)
+        }
+    }
+    return fingers
+}
+
+// convertNucleiMatchers 将一组 matcher 转换为一个指纹，单个 word/regex/favicon matcher 转换为普通规则，
+// 多个 matcher 或取反的 matcher 转换为 match 表达式；status 等无法判断的条件被忽略
+func convertNucleiMatchers(cms string, matchers []nucleiMatcher, condition string, report *importReport) (config.Fingerprint, bool) {
+    var exprs []string
+    var simple []config.Fingerprint
+    for _, matcher := range matchers {
+        if matcher.Type == "status" || matcher.Type == "size" {
+            continue
+        }
+        fp, expr, err := convertNucleiMatcher(cms, matcher)
+        if err != nil {
+            if condition == "and" {
+                report.skip(cms, "%v", err)
+                return config.Fingerprint{}, false
+            }
+            report.drop(cms, "%v", err)
+            continue
+        }
+        exprs = append(exprs, expr)
+        if !matcher.Negative {
+            simple = append(simple, fp)
+        }
+    }
+    switch {
+    case len(exprs) == 0:
+        report.skip(cms, "no convertible matchers")
+        return config.Fingerprint{}, false
+    case len(exprs) == 1 && len(simple) == 1:
+        return simple[0], true
+    }
+    joiner := " || "
+    if condition == "and" {
+        joiner = " && "
+    }
+    for i, expr := range exprs {
+        exprs[i] = "(" + expr + ")"
+    }
+    return config.Fingerprint{CMS: cms, Match: strings.Join(exprs, joiner), Rule: []string{}}, true
+}
+
+// convertNucleiMatcher 转换单个 matcher，同时返回等价的普通规则和 match 表达式
+func convertNucleiMatcher(cms string, matcher nucleiMatcher) (config.Fingerprint, string, error) {
+    fp := config.Fingerprint{CMS: cms, Logic: "or"}
+    if matcher.Condition == "and" {
+        fp.Logic = "and"
+    }
+    var location, op string
+    switch matcher.Type {
+    case "word":
+        loc, ok := nucleiLocation(matcher.Part)
+        if !ok {
+            return fp, "", fmt.Errorf("unsupported part %q", matcher.Part)
+        }
+        location, op = loc, "="
+        fp.Method = "keyword"
+        fp.Rule = matcher.Words
+        if matcher.CaseInsensitive {
+            op = "~="
+            fp.Method = "regex"
+            fp.Rule = nil
+            for _, word := range matcher.Words {
+                fp.Rule = append(fp.Rule, "(?i)"+regexp.QuoteMeta(word))
+            }
+        }
+    case "regex":
+        loc, ok := nucleiLocation(matcher.Part)
+        if !ok {
+            return fp, "", fmt.Errorf("unsupported part %q", matcher.Part)
+        }
+        location, op = loc, "~="
+        fp.Method = "regex"
+        fp.Rule = matcher.Regex
+    case "dsl":
+        location, op = "faviconhash", "="
+        fp.Method = "faviconhash"
+        fp.Location = "body"
+        for _, dsl := range matcher.DSL {
+            for _, pattern := range nucleiFaviconHash {
+                for _, m := range pattern.FindAllStringSubmatch(dsl, -1) {
+                    fp.Rule = append(fp.Rule, m[1])
+                }
+            }
+        }
+        if len(fp.Rule) == 0 {
+            return fp, "", fmt.Errorf("unsupported dsl %q", strings.Join(matcher.DSL, " "))
+        }
+        // dsl 中的多个 favicon 哈希是可选的
+        fp.Logic = "or"
+    case "favicon":
+        return fp, "", fmt.Errorf("favicon matcher uses %d md5 hashes, only mmh3 is supported", len(matcher.Hash))
+    default:
+        return fp, "", fmt.Errorf("unsupported matcher type %q", matcher.Type)
+    }
+    if len(fp.Rule) == 0 {
+        return fp, "", fmt.Errorf("empty %s matcher", matcher.Type)
+    }
+    if fp.Method != "faviconhash" {
+        fp.Location = location
+    }
+
+    conditions := make([]string, len(fp.Rule))
+    for i, rule := range fp.Rule {
+        conditions[i] = location + op + `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(rule) + `"`
+    }
+    joiner := " || "
+    if fp.Logic == "and" {
+        joiner = " && "
+    }
+    expr := strings.Join(conditions, joiner)
+    if matcher.Negative {
+        expr = "!(" + expr + ")"
+    }
+    return fp, expr, nil
+}
+
+// nucleiProductName 去掉模板名称中常见的 Detect、Detection 后缀，得到产品名称
+func nucleiProductName(name string) string {
+    name = strings.TrimSpace(name)
+    for _, suffix := range []string{" - Detect", " - Detection", " Detection", " Detect"} {
+        if len(name) > len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+            return strings.TrimSpace(name[:len(name)-len(suffix)])
+        }
+    }
+    return name
+}
+
+// nucleiLocation 将 nuclei 的 part 映射为 hfinger 的匹配位置，完整响应按 body 处理
+func nucleiLocation(part string) (string, bool) {
+    switch part {
+    case "", "body", "all", "response", "raw":
+        return "body", true
+    case "header":
+        return "header", true
+    }
+    return "", false
+}
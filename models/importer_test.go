@@ -0,0 +1,316 @@
+package models
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "hfinger/config"
+)
+
+const testEHoleFingers = `{"fingerprint": [
+    {"cms": "seeyon", "method": "keyword", "location": "body", "keyword": ["/seeyon/USER-DATA/IMAGES/LOGIN/login.gif"]},
+    {"cms": "Shiro", "method": "keyword", "location": "header", "keyword": ["rememberMe=", "deleteMe"]},
+    {"cms": "Jenkins", "method": "faviconhash", "location": "body", "keyword": ["81586312"]},
+    {"cms": "Weblogic", "method": "regula", "location": "title", "keyword": ["Error 40[34]"]},
+    {"cms": "Unknown method", "method": "icon_hash", "location": "body", "keyword": ["1"]},
+    {"cms": "Unknown location", "method": "keyword", "location": "url", "keyword": ["/admin"]},
+    {"cms": "No keywords", "method": "keyword", "location": "body", "keyword": []}
+]}`
+
+const testWappalyzerTechnologies = `{"technologies": {
+    "Nginx": {
+        "headers": {"Server": "nginx(?:/([\\d.]+))?\\;version:\\1"},
+        "cpe": "cpe:2.3:a:f5:nginx:*:*:*:*:*:*:*:*"
+    },
+    "Shiro": {
+        "cookies": {"rememberMe": ""}
+    },
+    "Discuz!": {
+        "html": "<div id=\"discuz_tips\"",
+        "meta": {"generator": "Discuz! X([\\d.]+)\\;version:\\1"},
+        "scriptSrc": "^/static/js/common\\.js",
+        "js": {"discuz_uid": ""}
+    },
+    "Let's Encrypt": {
+        "certIssuer": "Let's Encrypt"
+    },
+    "React": {
+        "js": {"React.version": ""}
+    },
+    "Broken": {
+        "html": "(?<=x)y"
+    }
+}}`
+
+const testFingerprintHubTemplate = `id: seeyon-oa
+info:
+  name: 致远OA
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/seeyon/index.jsp"
+    matchers:
+      - type: word
+        words:
+          - "/seeyon/common/"
+          - "seeyon"
+        condition: and
+`
+
+const testNucleiTechDetect = `id: tech-detect
+info:
+  name: Wappalyzer Technology Detection
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers-condition: or
+    matchers:
+      - type: word
+        name: jenkins
+        part: header
+        words:
+          - "X-Jenkins"
+      - type: regex
+        name: php
+        part: header
+        regex:
+          - "PHP/[0-9.]+"
+      - type: word
+        name: grafana
+        case-insensitive: true
+        words:
+          - "grafana-app"
+`
+
+const testNucleiFavicon = `id: favicon-detect
+info:
+  name: Favicon Detection
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/favicon.ico"
+    matchers:
+      - type: dsl
+        name: jenkins
+        dsl:
+          - "status_code==200 && (\"81586312\" == mmh3(base64_py(body)))"
+`
+
+const testNucleiCombined = `id: gitlab-detect
+info:
+  name: GitLab - Detect
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/users/sign_in"
+    matchers-condition: and
+    matchers:
+      - type: word
+        words:
+          - "GitLab"
+      - type: word
+        part: header
+        negative: true
+        words:
+          - "X-Honeypot"
+      - type: status
+        status:
+          - 200
+    extractors:
+      - type: regex
+        group: 1
+        regex:
+          - "gitlab_version\":\"([0-9.]+)"
+`
+
+const testNucleiUnsupported = `id: raw-detect
+info:
+  name: Raw Detect
+http:
+  - raw:
+      - |
+        GET / HTTP/1.1
+        Host: {{Hostname}}
+    matchers:
+      - type: word
+        words:
+          - "raw"
+`
+
+// writeImportFixture 将测试用的第三方指纹写入临时目录，返回文件路径
+func writeImportFixture(t *testing.T, name string, content string) string {
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// checkImported 逐条比较转换结果的匹配字段，并检查每条指纹都能被加载
+func checkImported(t *testing.T, got []config.Fingerprint, want []config.Fingerprint) {
+    t.Helper()
+    if len(got) != len(want) {
+        t.Fatalf("converted %d fingerprints, want %d: %+v", len(got), len(want), got)
+    }
+    for i := range want {
+        if !reflect.DeepEqual(got[i], want[i]) {
+            t.Errorf("fingerprint #%d =\n%+v\nwant\n%+v", i, got[i], want[i])
+        }
+        if err := config.ValidateFingerprint(got[i]); err != nil {
+            t.Errorf("fingerprint #%d (%s) cannot be loaded: %v", i, got[i].CMS, err)
+        }
+    }
+}
+
+// checkReport 检查转换报告中记录的条目名称
+func checkReport(t *testing.T, entries []string, want ...string) {
+    t.Helper()
+    if len(entries) != len(want) {
+        t.Fatalf("report = %q, want entries for %q", entries, want)
+    }
+    for i, name := range want {
+        if !strings.HasPrefix(entries[i], name+": ") {
+            t.Errorf("report entry #%d = %q, want it for %s", i, entries[i], name)
+        }
+    }
+}
+
+// TestImportEHole 转换 EHole 指纹，不支持的方法、位置和空关键词被跳过
+func TestImportEHole(t *testing.T) {
+    report := &importReport{}
+    fingers, err := importEHole(writeImportFixture(t, "finger.json", testEHoleFingers), report)
+    if err != nil {
+        t.Fatal(err)
+    }
+    checkImported(t, fingers, []config.Fingerprint{
+        {CMS: "seeyon", Method: "keyword", Location: "body", Logic: "and", Rule: []string{"/seeyon/USER-DATA/IMAGES/LOGIN/login.gif"}},
+        {CMS: "Shiro", Method: "keyword", Location: "header", Logic: "and", Rule: []string{"rememberMe=", "deleteMe"}},
+        {CMS: "Jenkins", Method: "faviconhash", Location: "body", Logic: "and", Rule: []string{"81586312"}},
+        {CMS: "Weblogic", Method: "regex", Location: "title", Logic: "and", Rule: []string{"Error 40[34]"}},
+    })
+    checkReport(t, report.skipped, "Unknown method", "Unknown location", "No keywords")
+    checkReport(t, report.partial)
+}
+
+// TestImportWappalyzer 转换 Wappalyzer 技术，只含 js 等字段的技术被跳过，部分字段不支持时记录为部分转换
+func TestImportWappalyzer(t *testing.T) {
+    report := &importReport{}
+    fingers, err := importWappalyzer(writeImportFixture(t, "technologies.json", testWappalyzerTechnologies), report)
+    if err != nil {
+        t.Fatal(err)
+    }
+    checkImported(t, fingers, []config.Fingerprint{
+        {CMS: "Discuz!", Method: "regex", Location: "body", Logic: "or",
+            Rule: []string{
+                `(?i)<div id="discuz_tips"`,
+                `(?i)<script[^>]+src=["']?[^"'>]*/static/js/common\.js`,
+                `(?i)<meta[^>]+name=["']?generator["']?[^>]+content=["']?[^"'>]*Discuz! X([\d.]+)`,
+            },
+            Version: []config.VersionRule{{Location: "body", Regex: `(?i)<meta[^>]+name=["']?generator["']?[^>]+content=["']?[^"'>]*Discuz! X([\d.]+)`, Group: 1}}},
+        {CMS: "Let's Encrypt", Method: "regex", Location: "cert", Logic: "or", Rule: []string{"(?i)Issuer: .*Let's Encrypt"}},
+        {CMS: "Nginx", Method: "regex", Location: "header", Logic: "or", Rule: []string{`Server~=(?i)nginx(?:/([\d.]+))?`},
+            Version: []config.VersionRule{{Location: "header", Header: "Server", Regex: `(?i)nginx(?:/([\d.]+))?`, Group: 1}}},
+        {CMS: "Shiro", Method: "regex", Location: "header", Logic: "or", Rule: []string{`Set-Cookie~=(?i)(?:^|;\s*)rememberMe=`}},
+    })
+    checkReport(t, report.skipped, "Broken", "React")
+    checkReport(t, report.partial, "Broken", "Discuz!")
+}
+
+// TestImportNuclei 转换 FingerprintHub 和 nuclei 模板，包括探测路径、tech-detect 的命名 matcher、
+// favicon dsl、取反 matcher 和版本提取
+func TestImportNuclei(t *testing.T) {
+    tests := []struct {
+        name     string
+        template string
+        named    bool
+        want     []config.Fingerprint
+        skipped  []string
+    }{
+        {
+            name:     "fingerprinthub",
+            template: testFingerprintHubTemplate,
+            want: []config.Fingerprint{
+                {CMS: "致远OA", Method: "keyword", Location: "body", Logic: "and", Rule: []string{"/seeyon/common/", "seeyon"}, Path: "/seeyon/index.jsp"},
+            },
+        },
+        {
+            name:     "tech-detect",
+            template: testNucleiTechDetect,
+            named:    true,
+            want: []config.Fingerprint{
+                {CMS: "jenkins", Method: "keyword", Location: "header", Logic: "or", Rule: []string{"X-Jenkins"}},
+                {CMS: "php", Method: "regex", Location: "header", Logic: "or", Rule: []string{"PHP/[0-9.]+"}},
+                {CMS: "grafana", Method: "regex", Location: "body", Logic: "or", Rule: []string{`(?i)grafana-app`}},
+            },
+        },
+        {
+            name:     "favicon dsl",
+            template: testNucleiFavicon,
+            named:    true,
+            want: []config.Fingerprint{
+                {CMS: "jenkins", Method: "faviconhash", Location: "body", Logic: "or", Rule: []string{"81586312"}},
+            },
+        },
+        {
+            name:     "negative matcher and extractor",
+            template: testNucleiCombined,
+            named:    true,
+            want: []config.Fingerprint{
+                {CMS: "GitLab", Match: `(body="GitLab") && (!(header="X-Honeypot"))`, Rule: []string{}, Path: "/users/sign_in",
+                    Version: []config.VersionRule{{Location: "body", Regex: `gitlab_version":"([0-9.]+)`, Group: 1}}},
+            },
+        },
+        {
+            name:     "raw request",
+            template: testNucleiUnsupported,
+            skipped:  []string{"Raw"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            report := &importReport{}
+            fingers, err := importNuclei(writeImportFixture(t, "template.yaml", tt.template), tt.named, report)
+            if err != nil {
+                t.Fatal(err)
+            }
+            checkImported(t, fingers, tt.want)
+            checkReport(t, report.skipped, tt.skipped...)
+        })
+    }
+}
+
+// TestImportFingerprints 导入结果写入文件后可以作为指纹库加载，与当前指纹库重复的条目被去除
+func TestImportFingerprints(t *testing.T) {
+    previous := config.Config
+    t.Cleanup(func() { config.Config = previous })
+    config.Config = &config.FingerprintConfig{Finger: []config.Fingerprint{
+        {CMS: "seeyon", Method: "keyword", Location: "body", Logic: "and", Rule: []string{"/seeyon/USER-DATA/IMAGES/LOGIN/login.gif"}},
+    }}
+
+    output := filepath.Join(t.TempDir(), "imported.json")
+    if err := ImportFingerprints("ehole", writeImportFixture(t, "finger.json", testEHoleFingers), output); err != nil {
+        t.Fatal(err)
+    }
+    data, err := os.ReadFile(output)
+    if err != nil {
+        t.Fatal(err)
+    }
+    imported, err := config.ParseFingerprintConfig(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var names []string
+    for _, fp := range imported.Finger {
+        names = append(names, fp.CMS)
+    }
+    if want := []string{"Shiro", "Jenkins", "Weblogic"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("imported %v, want %v", names, want)
+    }
+    if err := ImportFingerprints("whatweb", output, output); err == nil {
+        t.Error("expected an error for an unsupported format")
+    }
+}
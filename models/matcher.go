@@ -1,19 +1,39 @@
 package models
 
 import (
+    "crypto/md5"
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
     "strings"
     "strconv"
 
     "hfinger/config"
 )
 
-// matchKeywords 根据规则进行匹配
-func matchKeywords(body []byte, header map[string][]string, title string, favicon []byte, fingerprint config.Fingerprint) bool {
+// matchKeywords 根据规则进行匹配，命中后再检查排除规则
+func matchKeywords(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingerprint config.Fingerprint) bool {
+    if !matchRules(body, header, title, cert, icons, fingerprint) {
+        return false
+    }
+    return !matchNot(body, header, title, cert, fingerprint)
+}
+
+// matchRules 根据 match 表达式或 method/location/logic/rule 进行匹配
+func matchRules(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, fingerprint config.Fingerprint) bool {
+    if fingerprint.Expr != nil {
+        return matchExpr(body, header, title, cert, icons, fingerprint.Expr)
+    }
     switch fingerprint.Method {
-    case "keyword":
+    case "keyword", "regex":
+        if fingerprint.Location == "cert" {
+            // 证书文本与 title 一样按单个字符串匹配
+            return cert != "" && matchTitle(cert, fingerprint)
+        }
         if body != nil {
             switch fingerprint.Location {
-            case "body":
+            case "body", "js", "css":
+                // js、css 指纹匹配时 body 为页面引用的脚本或样式表内容
                 return matchBody(body, fingerprint)
             case "header":
                 return matchHeader(header, fingerprint)
@@ -21,15 +41,85 @@ func matchKeywords(body []byte, header map[string][]string, title string, favico
                 return matchTitle(title, fingerprint)
             }
         }
+    case "banner":
+        // banner 指纹匹配时 body 为 TCP 服务返回的原始内容
+        if body != nil {
+            return matchBody(body, fingerprint)
+        }
+        return false
+    case "filehash":
+        if body != nil {
+            _, matched := fileHashVersion(body, fingerprint)
+            return matched
+        }
+        return false
     case "faviconhash":
-        if favicon != nil {
-            icon_hash := mmh3Hash32(favicon)
-            inticon_hash,_ := strconv.ParseInt(icon_hash, 10, 32)
-            for _, rule := range fingerprint.Rule {
-                intrule,_ := strconv.ParseInt(rule, 10, 32)
-                if int32(inticon_hash) == int32(intrule) {
-                    return true
-                }
+        _, matched := matchedIcon(icons, fingerprint)
+        return matched
+    }
+    return false
+}
+
+// matchedIcon 返回第一个哈希命中 faviconhash 规则的图标
+func matchedIcon(icons []iconInfo, fingerprint config.Fingerprint) (iconInfo, bool) {
+    for _, icon := range icons {
+        iconHash, _ := strconv.ParseInt(icon.Hash, 10, 32)
+        for _, rule := range fingerprint.Rule {
+            ruleHash, _ := strconv.ParseInt(rule, 10, 32)
+            if int32(iconHash) == int32(ruleHash) {
+                return icon, true
+            }
+        }
+    }
+    return iconInfo{}, false
+}
+
+// productIcon 返回已命中的指纹所依据的图标，match 表达式中取第一个非取反的 faviconhash 条件
+func productIcon(icons []iconInfo, fingerprint config.Fingerprint) (iconInfo, bool) {
+    if len(icons) == 0 {
+        return iconInfo{}, false
+    }
+    if fingerprint.Expr != nil {
+        return exprIcon(icons, fingerprint.Expr)
+    }
+    if fingerprint.Method != "faviconhash" {
+        return iconInfo{}, false
+    }
+    return matchedIcon(icons, fingerprint)
+}
+
+func exprIcon(icons []iconInfo, expr *config.Expr) (iconInfo, bool) {
+    switch expr.Op {
+    case "and", "or":
+        if icon, ok := exprIcon(icons, expr.Left); ok {
+            return icon, true
+        }
+        return exprIcon(icons, expr.Right)
+    case "leaf":
+        if expr.Leaf.Method == "faviconhash" {
+            return matchedIcon(icons, *expr.Leaf)
+        }
+    }
+    return iconInfo{}, false
+}
+
+// matchIndexed 根据自动机命中的模式 ID 计算 and/or 逻辑
+func matchIndexed(ruleIDs []int32, hits []bool, logic string) bool {
+    if hits == nil {
+        return false
+    }
+    switch logic {
+    case "and":
+        for _, id := range ruleIDs {
+            if !hits[id] {
+                return false
+            }
+        }
+        return true
+    case "or":
+        for _, id := range ruleIDs {
+            if hits[id] {
+                return true
             }
         }
         return false
@@ -37,20 +127,101 @@ func matchKeywords(body []byte, header map[string][]string, title string, favico
     return false
 }
 
+// matchNot 判断 location 中是否出现任意一条排除规则
+func matchNot(body []byte, header map[string][]string, title string, cert string, fingerprint config.Fingerprint) bool {
+    if len(fingerprint.Not) == 0 {
+        return false
+    }
+    method := "keyword"
+    if fingerprint.Method == "regex" {
+        method = "regex"
+    }
+    exclusion := config.Fingerprint{
+        Method:      method,
+        Logic:       "or",
+        Rule:        fingerprint.Not,
+        Regexps:     fingerprint.NotRegexps,
+        HeaderRules: fingerprint.NotHeaderRules,
+    }
+    switch fingerprint.Location {
+    case "body", "js", "css", "banner":
+        return body != nil && matchBody(body, exclusion)
+    case "header":
+        return matchHeader(header, exclusion)
+    case "title":
+        return matchTitle(title, exclusion)
+    case "cert":
+        return matchTitle(cert, exclusion)
+    }
+    return false
+}
+
+// matchExpr 对 match 表达式求值，叶子节点复用 matchKeywords 中的匹配器
+func matchExpr(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, expr *config.Expr) bool {
+    switch expr.Op {
+    case "and":
+        return matchExpr(body, header, title, cert, icons, expr.Left) && matchExpr(body, header, title, cert, icons, expr.Right)
+    case "or":
+        return matchExpr(body, header, title, cert, icons, expr.Left) || matchExpr(body, header, title, cert, icons, expr.Right)
+    case "not":
+        // 取反的条件只在位置有内容时成立，避免 body!="x" 或 faviconhash!="x" 命中没有 body 或图标的响应
+        if expr.Left.Op == "leaf" && !exprHasContent(body, header, title, cert, icons, expr.Left.Leaf.Location) {
+            return false
+        }
+        return !matchExpr(body, header, title, cert, icons, expr.Left)
+    case "leaf":
+        return matchKeywords(body, header, title, cert, icons, *expr.Leaf)
+    }
+    return false
+}
+
+// exprHasContent 判断表达式叶子节点的匹配位置是否有内容，header 和 title 与 matchRules 一样要求有响应
+func exprHasContent(body []byte, header map[string][]string, title string, cert string, icons []iconInfo, location string) bool {
+    switch location {
+    case "body":
+        return len(body) > 0
+    case "header":
+        return body != nil && len(header) > 0
+    case "title":
+        return body != nil && title != ""
+    case "cert":
+        return cert != ""
+    case "faviconhash":
+        for _, icon := range icons {
+            if icon.Hash != "" {
+                return true
+            }
+        }
+        return false
+    }
+    return true
+}
+
+// matchRule 按 method 判断单条规则是否命中，regex 使用加载时预编译的正则
+func matchRule(content string, fingerprint config.Fingerprint, index int) bool {
+    if fingerprint.Method == "regex" {
+        if index >= len(fingerprint.Regexps) {
+            return false
+        }
+        return fingerprint.Regexps[index].MatchString(content)
+    }
+    return strings.Contains(content, fingerprint.Rule[index])
+}
+
 // matchBody 根据规则匹配 body
 func matchBody(body []byte, fingerprint config.Fingerprint) bool {
     bodyStr := string(body)
     switch fingerprint.Logic {
     case "and":
-        for _, rule := range fingerprint.Rule {
-            if !strings.Contains(bodyStr, rule) {
+        for i := range fingerprint.Rule {
+            if !matchRule(bodyStr, fingerprint, i) {
                 return false
             }
         }
         return true
     case "or":
-        for _, rule := range fingerprint.Rule {
-            if strings.Contains(bodyStr, rule) {
+        for i := range fingerprint.Rule {
+            if matchRule(bodyStr, fingerprint, i) {
                 return true
             }
         }
@@ -63,36 +234,16 @@ func matchBody(body []byte, fingerprint config.Fingerprint) bool {
 func matchHeader(header map[string][]string, fingerprint config.Fingerprint) bool {
     switch fingerprint.Logic {
     case "and":
-        for _, rule := range fingerprint.Rule {
-            matched := false
-            for key, values := range header {
-                if strings.Contains(key, rule) {
-                    matched = true
-                    break
-                }
-                for _, value := range values {
-                    if strings.Contains(value, rule) {
-                        matched = true
-                        break
-                    }
-                }
-            }
-            if !matched {
+        for i := range fingerprint.Rule {
+            if !matchHeaderRule(header, fingerprint, i) {
                 return false
             }
         }
         return true
     case "or":
-        for _, rule := range fingerprint.Rule {
-            for key, values := range header {
-                if strings.Contains(key, rule) {
-                    return true
-                }
-                for _, value := range values {
-                    if strings.Contains(value, rule) {
-                        return true
-                    }
-                }
+        for i := range fingerprint.Rule {
+            if matchHeaderRule(header, fingerprint, i) {
+                return true
             }
         }
         return false
@@ -100,19 +251,61 @@ func matchHeader(header map[string][]string, fingerprint config.Fingerprint) boo
     return false
 }
 
+// matchHeaderRule 匹配单条 header 规则，指定了头名称时只匹配该头的值，否则匹配所有头名称和值
+func matchHeaderRule(header map[string][]string, fingerprint config.Fingerprint, index int) bool {
+    _, _, matched := findHeaderRule(header, fingerprint, index)
+    return matched
+}
+
+// findHeaderRule 返回单条 header 规则命中的头名称和值
+func findHeaderRule(header map[string][]string, fingerprint config.Fingerprint, index int) (string, string, bool) {
+    if index < len(fingerprint.HeaderRules) && fingerprint.HeaderRules[index].Name != "" {
+        headerRule := fingerprint.HeaderRules[index]
+        for _, value := range http.Header(header).Values(headerRule.Name) {
+            switch {
+            case headerRule.Pattern != nil:
+                if headerRule.Pattern.MatchString(value) {
+                    return headerRule.Name, value, true
+                }
+            case fingerprint.Method == "regex":
+                if index < len(fingerprint.Regexps) && fingerprint.Regexps[index].MatchString(value) {
+                    return headerRule.Name, value, true
+                }
+            default:
+                if strings.Contains(value, headerRule.Value) {
+                    return headerRule.Name, value, true
+                }
+            }
+        }
+        return "", "", false
+    }
+
+    for key, values := range header {
+        if matchRule(key, fingerprint, index) {
+            return key, strings.Join(values, ", "), true
+        }
+        for _, value := range values {
+            if matchRule(value, fingerprint, index) {
+                return key, value, true
+            }
+        }
+    }
+    return "", "", false
+}
+
 // matchTitle 根据规则匹配 title
 func matchTitle(title string, fingerprint config.Fingerprint) bool {
     switch fingerprint.Logic {
     case "and":
-        for _, rule := range fingerprint.Rule {
-            if !strings.Contains(title, rule) {
+        for i := range fingerprint.Rule {
+            if !matchRule(title, fingerprint, i) {
                 return false
             }
         }
         return true
     case "or":
-        for _, rule := range fingerprint.Rule {
-            if strings.Contains(title, rule) {
+        for i := range fingerprint.Rule {
+            if matchRule(title, fingerprint, i) {
                 return true
             }
         }
@@ -120,3 +313,56 @@ func matchTitle(title string, fingerprint config.Fingerprint) bool {
     }
     return false
 }
+
+// fileHashVersion 计算资源的 md5 和 sha256 摘要，返回命中摘要对应的版本
+func fileHashVersion(body []byte, fingerprint config.Fingerprint) (string, bool) {
+    md5Sum := md5.Sum(body)
+    if version, ok := fingerprint.Hashes[hex.EncodeToString(md5Sum[:])]; ok {
+        return version, true
+    }
+    sha256Sum := sha256.Sum256(body)
+    if version, ok := fingerprint.Hashes[hex.EncodeToString(sha256Sum[:])]; ok {
+        return version, true
+    }
+    return "", false
+}
+
+// extractVersion 按指纹中定义的版本规则提取版本号，未提取到时返回空字符串
+// filehash 指纹的版本由命中的摘要决定
+func extractVersion(body []byte, header map[string][]string, title string, cert string, fingerprint config.Fingerprint) string {
+    if fingerprint.Method == "filehash" {
+        if version, matched := fileHashVersion(body, fingerprint); matched {
+            return version
+        }
+    }
+    for _, vr := range fingerprint.Version {
+        if vr.Pattern == nil {
+            continue
+        }
+        var candidates []string
+        switch vr.Location {
+        case "body", "js", "css", "banner":
+            candidates = []string{string(body)}
+        case "title":
+            candidates = []string{title}
+        case "cert":
+            candidates = []string{cert}
+        case "header":
+            if vr.Header != "" {
+                candidates = http.Header(header).Values(vr.Header)
+            } else {
+                for _, values := range header {
+                    candidates = append(candidates, values...)
+                }
+            }
+        }
+        for _, content := range candidates {
+            if m := vr.Pattern.FindStringSubmatch(content); m != nil {
+                if version := strings.TrimSpace(m[vr.Group]); version != "" {
+                    return version
+                }
+            }
+        }
+    }
+    return ""
+}
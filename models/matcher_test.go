@@ -0,0 +1,354 @@
+package models
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "hfinger/config"
+)
+
+// loadLibrary 加载仓库中的指纹库并替换 config.Config，测试结束后恢复原有指纹库
+func loadLibrary(tb testing.TB) *config.FingerprintConfig {
+    data, err := os.ReadFile(filepath.Join("..", "data", "finger.json"))
+    if err != nil {
+        tb.Skipf("fingerprint library not available: %v", err)
+    }
+    fingerConfig, err := config.ParseFingerprintConfig(data)
+    if err != nil {
+        tb.Fatal(err)
+    }
+    previous := config.Config
+    config.Config = fingerConfig
+    tb.Cleanup(func() { config.Config = previous })
+    return fingerConfig
+}
+
+// loadBenchmarkFingerprints 加载仓库中的指纹库，并构造一个包含部分规则关键词的响应
+func loadBenchmarkFingerprints(b *testing.B) ([]byte, map[string][]string, string) {
+    loadLibrary(b)
+    return benchmarkResponse()
+}
+
+// benchmarkResponse 构造一个约 64KB、包含部分常见规则关键词的响应
+func benchmarkResponse() ([]byte, map[string][]string, string) {
+    var body bytes.Buffer
+    body.WriteString("<!DOCTYPE html><html><head><title>Welcome</title></head><body>")
+    for body.Len() < 64*1024 {
+        body.WriteString(`<div class="container"><p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>`)
+        body.WriteString(`<script src="/static/js/app.js"></script><a href="/seeyon/common/">link</a></div>`)
+    }
+    body.WriteString("</body></html>")
+
+    header := map[string][]string{
+        "Server":       {"nginx/1.24.0"},
+        "Content-Type": {"text/html; charset=utf-8"},
+        "Set-Cookie":   {"JSESSIONID=0123456789ABCDEF; Path=/; HttpOnly", "rememberMe=deleteMe"},
+        "X-Powered-By": {"PHP/8.1.2"},
+    }
+    return body.Bytes(), header, "Welcome"
+}
+
+// BenchmarkMatchLinear 逐条指纹调用 matchKeywords 的原有匹配方式
+func BenchmarkMatchLinear(b *testing.B) {
+    body, header, title := loadBenchmarkFingerprints(b)
+    b.SetBytes(int64(len(body)))
+    b.ResetTimer()
+    for n := 0; n < b.N; n++ {
+        for _, fingerprint := range config.Config.Finger {
+            matchKeywords(body, header, title, "", nil, fingerprint)
+        }
+    }
+}
+
+// BenchmarkMatchIndexed 关键词规则通过自动机一次扫描后再计算逻辑
+func BenchmarkMatchIndexed(b *testing.B) {
+    body, header, title := loadBenchmarkFingerprints(b)
+    b.SetBytes(int64(len(body)))
+    b.ResetTimer()
+    for n := 0; n < b.N; n++ {
+        matchProducts(body, header, title, "", nil, nil)
+    }
+}
+
+// libraryResponse 使用指纹库中 pick 选中的指纹的规则构造响应，keep 返回每条指纹保留的规则数
+func libraryResponse(fingerConfig *config.FingerprintConfig, pick func(int) bool, keep func(int, int) int) ([]byte, map[string][]string, string) {
+    var body, title strings.Builder
+    header := map[string][]string{}
+    for i, fp := range fingerConfig.Finger {
+        if !pick(i) || fp.Match != "" {
+            continue
+        }
+        rules := fp.Rule[:keep(i, len(fp.Rule))]
+        switch fp.Location {
+        case "body":
+            for _, rule := range rules {
+                body.WriteString(rule + "\n")
+            }
+        case "title":
+            for _, rule := range rules {
+                title.WriteString(rule + " ")
+            }
+        case "header":
+            for j := range rules {
+                if j >= len(fp.HeaderRules) {
+                    break
+                }
+                name := fp.HeaderRules[j].Name
+                if name == "" {
+                    name = fmt.Sprintf("X-Sample-%d", i)
+                }
+                header[name] = append(header[name], fp.HeaderRules[j].Value)
+            }
+        }
+    }
+    return []byte(body.String()), header, title.String()
+}
+
+// TestMatchIndexedEqualsLinear 对整个指纹库，自动机索引匹配和逐条调用 matchKeywords 的结果必须一致
+func TestMatchIndexedEqualsLinear(t *testing.T) {
+    fingerConfig := loadLibrary(t)
+    type response struct {
+        body   []byte
+        header map[string][]string
+        title  string
+    }
+    samples := map[string]response{}
+    body, header, title := benchmarkResponse()
+    samples["benchmark"] = response{body, header, title}
+    body, header, title = libraryResponse(fingerConfig, func(i int) bool { return i%2 == 0 }, func(_, n int) int { return n })
+    samples["every rule of half the library"] = response{body, header, title}
+    samples["upper case"] = response{bytes.ToUpper(body), header, strings.ToUpper(title)}
+    body, header, title = libraryResponse(fingerConfig, func(i int) bool { return i%3 == 0 }, func(_, n int) int { return 1 })
+    samples["first rule only"] = response{body, header, title}
+    body, header, title = libraryResponse(fingerConfig, func(int) bool { return true }, func(i, n int) int { return n - i%n })
+    samples["whole library with rules dropped"] = response{body, header, title}
+
+    for name, sample := range samples {
+        t.Run(name, func(t *testing.T) {
+            hits := fingerConfig.Index.Scan(sample.body, sample.header, sample.title)
+            matched := 0
+            for i, fp := range fingerConfig.Finger {
+                ruleIDs := fingerConfig.Index.RuleIDs(i)
+                if ruleIDs == nil {
+                    continue
+                }
+                indexed := matchIndexed(ruleIDs, hits[fp.Location], fp.Logic) && !matchNot(sample.body, sample.header, sample.title, "", fp)
+                linear := matchKeywords(sample.body, sample.header, sample.title, "", nil, fp)
+                if indexed != linear {
+                    t.Errorf("fingerprint #%d (%s): indexed = %v, linear = %v", i, fp.CMS, indexed, linear)
+                }
+                if linear {
+                    matched++
+                }
+            }
+            if name != "benchmark" && matched == 0 {
+                t.Error("sample matched no fingerprint")
+            }
+
+            var linear []matchedProduct
+            for _, i := range fingerConfig.RootFingers {
+                fp := fingerConfig.Finger[i]
+                if matchKeywords(sample.body, sample.header, sample.title, "", nil, fp) {
+                    linear = mergeProducts(linear, []matchedProduct{{CMS: fp.CMS, Version: extractVersion(sample.body, sample.header, sample.title, "", fp)}})
+                }
+            }
+            indexed := matchConfigProducts(fingerConfig, sample.body, sample.header, sample.title, "", nil, nil)
+            if productNames(indexed) != productNames(linear) {
+                t.Errorf("indexed products %s, linear products %s", productNames(indexed), productNames(linear))
+            }
+        })
+    }
+}
+
+func productNames(products []matchedProduct) string {
+    names := make([]string, len(products))
+    for i, product := range products {
+        names[i] = product.CMS + " " + product.Version
+    }
+    return strings.Join(names, ", ")
+}
+
+// TestTildeOperator ~= 在 header 规则和 match 表达式中都是正则匹配，与指纹的 method 无关
+func TestTildeOperator(t *testing.T) {
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "keyword rule", "method": "keyword", "location": "header", "logic": "or", "rule": ["Set-Cookie~=^rememberMe=\\w+"]},
+        {"cms": "regex rule", "method": "regex", "location": "header", "logic": "or", "rule": ["Set-Cookie~=^rememberMe=\\w+"]},
+        {"cms": "expression", "match": "header~=\"Set-Cookie: ^rememberMe=\\\\w+\""}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(fingerConfig.Finger) != 3 {
+        t.Fatalf("loaded %d fingerprints, want 3", len(fingerConfig.Finger))
+    }
+    tests := []struct {
+        cookie string
+        want   bool
+    }{
+        {"rememberMe=deleteMe", true},
+        {"rememberMe=", false},
+        {"JSESSIONID=1; rememberMe=deleteMe", false},
+        {"REMEMBERME=deleteMe", false},
+        {`rememberMe=\w+`, false},
+    }
+    for _, tt := range tests {
+        header := map[string][]string{"Set-Cookie": {tt.cookie}}
+        for _, fp := range fingerConfig.Finger {
+            if got := matchKeywords([]byte{}, header, "", "", nil, fp); got != tt.want {
+                t.Errorf("%s: match %q = %v, want %v", fp.CMS, tt.cookie, got, tt.want)
+            }
+        }
+    }
+}
+
+// TestMatchHeaderRule 指定了头名称的规则只匹配该头的值，未指定时匹配所有头名称和值，~= 规则区分大小写，加 (?i) 后忽略大小写
+func TestMatchHeaderRule(t *testing.T) {
+    header := map[string][]string{
+        "Server":     {"nginx/1.24.0"},
+        "Via":        {"1.1 Server: apache"},
+        "Set-Cookie": {"JSESSIONID=1; Path=/", "rememberMe=deleteMe"},
+    }
+    tests := []struct {
+        method string
+        rule   string
+        want   bool
+    }{
+        {"keyword", "Server: nginx", true},
+        {"keyword", "server: nginx", true},
+        {"keyword", "Server: apache", false},
+        {"keyword", "Server: NGINX", false},
+        {"keyword", "Set-Cookie: rememberMe", true},
+        {"keyword", "X-Missing: nginx", false},
+        {"keyword", "JSESSIONID", true},
+        {"keyword", "Set-Cookie", true},
+        {"keyword", "apache", true},
+        {"regex", "Server: ^nginx/1\\.2", true},
+        {"regex", "Server: ^1\\.2", false},
+        {"regex", "Set-Cookie: ^rememberMe=", true},
+        {"keyword", "Server~=^NGINX/", false},
+        {"keyword", "Server~=(?i)^NGINX/", true},
+    }
+    for _, tt := range tests {
+        fingerConfig, err := config.ParseFingerprintConfig([]byte(fmt.Sprintf(`{"finger": [{"cms": "demo", "method": %q, "location": "header", "logic": "or", "rule": [%q]}]}`, tt.method, tt.rule)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := matchKeywords([]byte{}, header, "", "", nil, fingerConfig.Finger[0]); got != tt.want {
+            t.Errorf("%s %q = %v, want %v", tt.method, tt.rule, got, tt.want)
+        }
+    }
+}
+
+// TestMatchNot 排除规则在指纹的 location 中按 method 匹配，命中任意一条即判定为不匹配
+func TestMatchNot(t *testing.T) {
+    fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "body keyword", "method": "keyword", "location": "body", "logic": "or", "rule": ["demo-app"], "not": ["demo-honeypot", "demo-test"]},
+        {"cms": "body regex", "method": "regex", "location": "body", "logic": "or", "rule": ["demo-app"], "not": ["demo-(honeypot|test)"]},
+        {"cms": "header", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: demo-app"], "not": ["X-Demo: demo-honeypot", "demo-test"]},
+        {"cms": "title", "method": "keyword", "location": "title", "logic": "or", "rule": ["demo-app"], "not": ["demo-honeypot", "demo-test"]},
+        {"cms": "expression", "match": "body=\"demo-app\" && body!=\"demo-honeypot\" && body!=\"demo-test\""}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        name    string
+        content string
+        extra   string
+        want    bool
+    }{
+        {"plain", "demo-app", "", true},
+        {"first exclusion", "demo-app", "demo-honeypot", false},
+        {"second exclusion", "demo-app", "demo-test", false},
+        {"no rule", "other", "", false},
+    }
+    for _, tt := range tests {
+        for _, fp := range fingerConfig.Finger {
+            body := []byte(tt.content + " " + tt.extra)
+            header := map[string][]string{"Server": {tt.content}}
+            if tt.extra != "" {
+                header["X-Demo"] = []string{tt.extra}
+            }
+            title := strings.TrimSpace(tt.content + " " + tt.extra)
+            if got := matchKeywords(body, header, title, "", nil, fp); got != tt.want {
+                t.Errorf("%s/%s: match = %v, want %v", tt.name, fp.CMS, got, tt.want)
+            }
+        }
+    }
+}
+
+// TestExtractVersion 版本规则按顺序尝试，取指定捕获组中第一个非空的版本
+func TestExtractVersion(t *testing.T) {
+    body := []byte(`<footer>Demo Portal v2.3.1 (build-)</footer>`)
+    header := map[string][]string{
+        "Server":         {"nginx/1.24.0"},
+        "X-Demo-Version": {"7.0.4"},
+    }
+    title := "Demo Portal 2.3"
+    tests := []struct {
+        name    string
+        version string
+        want    string
+    }{
+        {"default group", `[{"location": "body", "regex": "Portal v([\\d.]+)"}]`, "2.3.1"},
+        {"second group", `[{"location": "body", "regex": "(Portal) v([\\d.]+)", "group": 2}]`, "2.3.1"},
+        {"whole match without group", `[{"location": "body", "regex": "\\d+\\.\\d+\\.\\d+"}]`, "2.3.1"},
+        {"named header", `[{"location": "header", "header": "x-demo-version", "regex": "([\\d.]+)"}]`, "7.0.4"},
+        {"any header", `[{"location": "header", "regex": "nginx/([\\d.]+)"}]`, "1.24.0"},
+        {"title", `[{"location": "title", "regex": "Portal ([\\d.]+)"}]`, "2.3"},
+        {"empty group falls through", `[{"location": "body", "regex": "build-(\\d*)"}, {"location": "title", "regex": "Portal ([\\d.]+)"}]`, "2.3"},
+        {"first match wins", `[{"location": "title", "regex": "Portal ([\\d.]+)"}, {"location": "body", "regex": "Portal v([\\d.]+)"}]`, "2.3"},
+        {"not found", `[{"location": "header", "header": "Server", "regex": "apache/([\\d.]+)"}]`, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fingerConfig, err := config.ParseFingerprintConfig([]byte(`{"finger": [{"cms": "demo", "method": "keyword", "location": "body", "logic": "or", "rule": ["Demo"], "version": ` + tt.version + `}]}`))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := extractVersion(body, header, title, "", fingerConfig.Finger[0]); got != tt.want {
+                t.Errorf("version = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestMatchExprNegation 取反的条件在位置没有内容时不成立，没有 body 或图标的响应不会命中 != 条件
+func TestMatchExprNegation(t *testing.T) {
+    header := map[string][]string{"Server": {"nginx"}}
+    tests := []struct {
+        match string
+        body  []byte
+        title string
+        icons []iconInfo
+        want  bool
+    }{
+        {`body!="demo"`, []byte("other"), "", nil, true},
+        {`body!="demo"`, []byte("demo"), "", nil, false},
+        {`body!="demo"`, nil, "", nil, false},
+        {`body!="demo"`, []byte{}, "", nil, false},
+        {`!body="demo"`, nil, "", nil, false},
+        {`!(body="demo")`, nil, "", nil, false},
+        {`body="a" || body!="b"`, nil, "", nil, false},
+        {`title!="OA"`, []byte("other"), "Portal", nil, true},
+        {`title!="OA"`, []byte("other"), "", nil, false},
+        {`header!="X-Demo"`, nil, "", nil, false},
+        {`cert!="Fortinet"`, []byte("other"), "", nil, false},
+        {`faviconhash!="123"`, []byte("other"), "", []iconInfo{{URL: "http://example.com/favicon.ico", Hash: "456"}}, true},
+        {`faviconhash!="123"`, []byte("other"), "", []iconInfo{{URL: "http://example.com/favicon.ico", Hash: "123"}}, false},
+        {`faviconhash!="123"`, []byte("other"), "", nil, false},
+    }
+    for _, tt := range tests {
+        fingerConfig, err := config.ParseFingerprintConfig([]byte(fmt.Sprintf(`{"finger": [{"cms": "demo", "match": %q}]}`, tt.match)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := matchKeywords(tt.body, header, tt.title, "", tt.icons, fingerConfig.Finger[0]); got != tt.want {
+            t.Errorf("%s with body %q, title %q and %d icons = %v, want %v", tt.match, tt.body, tt.title, len(tt.icons), got, tt.want)
+        }
+    }
+}
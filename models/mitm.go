@@ -9,6 +9,7 @@ import (
     "net"
     "net/http"
     "net/http/httputil"
+    neturl "net/url"
     "strings"
     "sync"
     "compress/gzip"
@@ -44,6 +45,7 @@ func MitmServer(listenAddr string) {
     defer listener.Close()
 
     logger.Info("Starting MITM Server at: %s", listenAddr)
+    watchFingerprints()
 
     for {
         conn, err := listener.Accept()
@@ -233,7 +235,7 @@ func handleHTTP2Request(w http.ResponseWriter, r *http.Request) {
     recorder := httptest.NewRecorder()
     
     // 转发请求
-    err := ForwardHTTP2Request(recorder, r)
+    certInfo, err := ForwardHTTP2Request(recorder, r)
     if err != nil {
         logger.PrintByLevel(err, fullURL)
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -252,7 +254,7 @@ func handleHTTP2Request(w http.ResponseWriter, r *http.Request) {
     }
     
     // 使用记录器中的数据安全地进行指纹匹配
-    go MitmMatchFingerprint(fullURL, recorder.Code, recorder.Header(), recorder.Body.Bytes())
+    go MitmMatchFingerprint(fullURL, recorder.Code, recorder.Header(), recorder.Body.Bytes(), certInfo)
 }
 
 // 转发器：根据方法 + 参数 返回 *http.Response
@@ -293,7 +295,8 @@ func forwardRequest(
     }
 }
 
-func ForwardHTTP2Request(w http.ResponseWriter, r *http.Request) error {
+// ForwardHTTP2Request 转发 HTTP/2 请求并写入 w，返回目标连接的证书
+func ForwardHTTP2Request(w http.ResponseWriter, r *http.Request) (*utils.CertInfo, error) {
     url := "https://" + r.Host + r.URL.String()
     headers := headersToMap(r.Header)
 
@@ -317,7 +320,7 @@ func ForwardHTTP2Request(w http.ResponseWriter, r *http.Request) error {
         },
     )
     if err != nil {
-        return err
+        return nil, err
     }
     defer resp.Body.Close()
 
@@ -326,7 +329,7 @@ func ForwardHTTP2Request(w http.ResponseWriter, r *http.Request) error {
     }
     w.WriteHeader(resp.StatusCode)
     _, err = io.Copy(w, resp.Body)
-    return err
+    return utils.PeerCertificate(resp), err
 }
 
 func ForwardHTTPRequest(conn net.Conn, req *http.Request, ishttps bool) error {
@@ -388,7 +391,7 @@ func handleTextResponse(conn net.Conn, resp *http.Response, url string) error {
         return err
     }
 
-    return MitmMatchFingerprint(url, resp.StatusCode, resp.Header, body)
+    return MitmMatchFingerprint(url, resp.StatusCode, resp.Header, body, utils.PeerCertificate(resp))
 }
 
 func handleBinaryResponse(conn net.Conn, resp *http.Response) error {
@@ -412,48 +415,83 @@ func isTextContent(contentType string) bool {
         strings.Contains(contentType, "x-www-form-urlencoded")
 }
 
-func MitmMatchFingerprint(url string, statuscode int, header http.Header, body []byte) error {
+// MitmMatchFingerprint 匹配代理转发的响应，certInfo 为目标连接的证书，非 HTTPS 时为 nil
+func MitmMatchFingerprint(url string, statuscode int, header http.Header, body []byte, certInfo *utils.CertInfo) error {
     debody, err := DecodeBody(header.Get("Content-Encoding"), body)
     if err != nil {
         return err
     }
-    faviconurl := utils.FetchFavicon(debody)
-    if strings.Contains(url, faviconurl) && statuscode == http.StatusOK {
-        matchfingerprint(url, statuscode, nil, header, debody)
+    // 浏览器会自行请求页面声明的各个图标，图片响应直接按图标计算哈希
+    if statuscode == http.StatusOK && isIconResponse(url, header, debody) {
+        matchfingerprint(url, statuscode, nil, header, []iconInfo{{URL: url, Hash: mmh3Hash32(debody)}}, certInfo)
     } else {
-        matchfingerprint(url, statuscode, debody, header, nil)
+        matchfingerprint(url, statuscode, debody, header, nil, certInfo)
     }
     return nil
 }
 
-func matchfingerprint(url string, statuscode int, body []byte, header http.Header, favicon []byte) {
-    var matched bool
-    cms := "None"
+// isIconResponse 判断响应是否为图片，Content-Type 缺失时根据 .ico 后缀和内容识别
+func isIconResponse(rawURL string, header http.Header, body []byte) bool {
+    if len(body) == 0 {
+        return false
+    }
+    if isImageContent(header.Get("Content-Type")) {
+        return true
+    }
+    if u, err := neturl.Parse(rawURL); err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".ico") {
+        return isImageContent(http.DetectContentType(body))
+    }
+    return false
+}
+
+func matchfingerprint(url string, statuscode int, body []byte, header http.Header, icons []iconInfo, certInfo *utils.CertInfo) {
     server := header.Get("Server")
     if server == "" {
         server = "None"
     }
-    title := utils.FetchTitle(body)
+    // 标题提取和关键词匹配使用转码为 UTF-8 的内容，filehash 指纹仍使用原始内容计算摘要
+    var text []byte
+    if body != nil {
+        text = utils.DecodeToUTF8(body, header.Get("Content-Type"))
+    }
+    title := utils.FetchTitle(text)
     if title == "" {
         title = "None"
     }
+    cert := certInfo.Text()
+    // 指纹库可能被热加载替换，同一响应的指纹下标和匹配必须使用同一个指纹库
+    fingerConfig := currentFingerprints()
+    fingers, hashFingers := fingersForURL(fingerConfig, url)
+    products := matchConfigProducts(fingerConfig, text, header, title, cert, icons, fingers)
+    if len(hashFingers) > 0 && body != nil {
+        products = mergeProducts(products, matchConfigProducts(fingerConfig, body, header, title, cert, nil, hashFingers))
+    }
+    var results []config.Result
+    for _, product := range products {
+        result := withCert(config.Result{
+            URL:        url,
+            CMS:        product.CMS,
+            Version:    product.Version,
+            Server:     server,
+            StatusCode: statuscode,
+            Title:      title,
+            IconURL:    product.IconURL,
+            IconHash:   product.IconHash,
+            Confidence: product.Confidence,
+        }, certInfo)
+        results = append(results, withProductInfo(withEvidence(result, product.Evidence), fingerConfig))
+    }
+    // 被动模式没有随机路径的响应，只检查是否为蜜罐
+    flagSuspiciousResults(url, results, pageSignature{}, pageSignature{}, nil)
     var newResults []config.Result
-    for _, fingerprint := range config.Config.Finger {
-        matched = matchKeywords(body, header, title, favicon, fingerprint)
-        if matched {
-            cms = fingerprint.CMS
-            key := fmt.Sprintf("%s::%s", url, cms)
-            if _, loaded := matchedCMS.LoadOrStore(key, true); !loaded {
-                logger.Success("[%s] [%s] [%d] [%s] [%s]", url, cms, statuscode, server, title)
-                result := config.Result{
-                    URL:        url,
-                    CMS:        cms,
-                    Server:     server,
-                    StatusCode: statuscode,
-                    Title:      title,
-                }
-                newResults = append(newResults, result)
+    for _, result := range resolveRelations(fingerConfig, results) {
+        key := fmt.Sprintf("%s::%s", url, result.CMS)
+        if _, loaded := matchedCMS.LoadOrStore(key, true); !loaded {
+            if !resultAllowed(result) {
+                continue
             }
+            logger.Success("[%s] [%s] [%d] [%s] [%s]%s%s", url, resultLabel(result), statuscode, server, title, resultFlags(result), evidenceLine(result))
+            newResults = append(newResults, result)
         }
     }
     if len(newResults) > 0 {
@@ -470,6 +508,35 @@ func matchfingerprint(url string, statuscode int, body []byte, header http.Heade
     }
 }
 
+// fingersForURL 返回被动模式下参与匹配的指纹，请求路径与探测路径一致时加入对应指纹
+// 第二个返回值为请求路径与 filehash 资源路径一致时需要使用原始响应体匹配的指纹
+func fingersForURL(fingerConfig *config.FingerprintConfig, rawURL string) ([]int, []int) {
+    parsedURL, err := neturl.Parse(rawURL)
+    if err != nil {
+        return fingerConfig.RootFingers, nil
+    }
+    fingers := fingerConfig.RootFingers
+    // 被动模式下脚本和样式表本身经过代理，直接与 js、css 指纹匹配
+    switch {
+    case strings.HasSuffix(parsedURL.Path, ".js"):
+        fingers = append(append([]int{}, fingers...), fingerConfig.ScriptFingers...)
+    case strings.HasSuffix(parsedURL.Path, ".css"):
+        fingers = append(append([]int{}, fingers...), fingerConfig.StyleFingers...)
+    }
+    for _, probe := range fingerConfig.Probes {
+        if probe.Method == "GET" && len(probe.Headers) == 0 && probe.Path == parsedURL.RequestURI() {
+            fingers = append(append([]int{}, fingers...), probe.Fingers...)
+        }
+    }
+    var hashFingers []int
+    for _, asset := range fingerConfig.Assets {
+        if asset.Path == parsedURL.RequestURI() {
+            hashFingers = append(hashFingers, asset.Fingers...)
+        }
+    }
+    return fingers, hashFingers
+}
+
 func DecodeBody(contentEncoding string, body []byte) ([]byte, error) {
     var reader io.ReadCloser
     var err error
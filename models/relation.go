@@ -0,0 +1,61 @@
+package models
+
+import "hfinger/config"
+
+// resolveRelations 根据指纹库中的 implies 和 excludes 补充推导出的产品并移除被排除的产品
+// 推导出的结果沿用推导来源的响应信息，不包含版本和命中依据
+func resolveRelations(fingerConfig *config.FingerprintConfig, results []config.Result) []config.Result {
+    if len(results) == 0 {
+        return results
+    }
+    observed := make([]string, len(results))
+    sources := make(map[string]config.Result)
+    for i, result := range results {
+        observed[i] = result.CMS
+        sources[result.CMS] = result
+    }
+    implied, excluded := fingerConfig.ResolveRelations(observed)
+    if len(implied) == 0 && len(excluded) == 0 {
+        return results
+    }
+
+    resolved := append([]config.Result(nil), results...)
+    for _, product := range implied {
+        result := impliedResult(sources[product.By], product)
+        sources[product.CMS] = result
+        resolved = append(resolved, withProductInfo(result, fingerConfig))
+    }
+    if len(excluded) == 0 {
+        return resolved
+    }
+    removed := make(map[string]bool)
+    for _, cms := range excluded {
+        removed[cms] = true
+    }
+    kept := resolved[:0]
+    for _, result := range resolved {
+        if !removed[result.CMS] {
+            kept = append(kept, result)
+        }
+    }
+    return kept
+}
+
+// impliedResult 以推导来源的结果为基础生成推导产品的结果
+func impliedResult(source config.Result, product config.ImpliedProduct) config.Result {
+    result := withEvidence(source, matchEvidence{})
+    result.CMS = product.CMS
+    result.Version = ""
+    result.IconURL, result.IconHash = "", ""
+    result.Implied = true
+    result.ImpliedBy = product.By
+    return result
+}
+
+// resultLabel 返回控制台输出的产品名称，推导出的产品标注推导来源
+func resultLabel(result config.Result) string {
+    if result.Implied {
+        return result.CMS + " (implied by " + result.ImpliedBy + ")"
+    }
+    return productLabel(result.CMS, result.Version)
+}
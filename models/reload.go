@@ -0,0 +1,109 @@
+package models
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "hfinger/config"
+    "hfinger/logger"
+)
+
+var (
+    reloadInterval = 2 * time.Second // 被动模式下检查指纹文件是否变化的间隔
+    liveConfig     atomic.Pointer[config.FingerprintConfig]
+)
+
+// currentFingerprints 返回被动模式当前使用的指纹库，未开启热加载时为启动时加载的指纹库
+func currentFingerprints() *config.FingerprintConfig {
+    if fingerConfig := liveConfig.Load(); fingerConfig != nil {
+        return fingerConfig
+    }
+    return config.Config
+}
+
+// watchFingerprints 定期检查官方指纹库、banner 指纹库、用户目录和 --finger 指定的文件
+// 文件变化时重新加载并校验，成功后原子替换当前指纹库，失败时继续使用原有指纹库
+func watchFingerprints() {
+    liveConfig.Store(config.Config)
+    state := fingerprintFileState()
+    go func() {
+        ticker := time.NewTicker(reloadInterval)
+        defer ticker.Stop()
+        for range ticker.C {
+            current := fingerprintFileState()
+            if current == state {
+                continue
+            }
+            // 无论成功与否都记录本次状态，损坏的文件只报错一次，再次修改后重新加载
+            state = current
+            reloadFingerprints()
+        }
+    }()
+}
+
+// fingerprintFileState 返回全部指纹文件的路径、大小和修改时间，用于判断文件是否变化
+func fingerprintFileState() string {
+    paths := []string{config.Fingerfullpath, config.Bannerfullpath}
+    if local, err := config.LocalFingerprintFiles(); err == nil {
+        paths = append(paths, local...)
+    }
+    var sb strings.Builder
+    for _, path := range paths {
+        sb.WriteString(path)
+        if info, err := os.Stat(path); err == nil {
+            fmt.Fprintf(&sb, " %d %d", info.Size(), info.ModTime().UnixNano())
+        }
+        sb.WriteByte('\n')
+    }
+    return sb.String()
+}
+
+// reloadFingerprints 重新加载指纹库，加载或校验失败时保留原有指纹库
+func reloadFingerprints() {
+    // 校验和加载使用同一次读取的文件内容，校验后文件再次被修改时不会加载未经校验的内容
+    fingerConfig, err := config.ReadValidatedFingerprintConfig()
+    if err != nil {
+        logger.Error("Error: Failed to reload fingerprint library, keeping the previous one: %v", err)
+        return
+    }
+
+    previous := currentFingerprints()
+    added, removed := diffFingerprints(previous, fingerConfig)
+    // 只替换 liveConfig，处理中的请求通过 currentFingerprints 取得快照，不直接读写 config.Config
+    liveConfig.Store(fingerConfig)
+    logger.Hint("Fingerprint library reloaded: %d added, %d removed, %d fingerprints in total",
+        added, removed, len(fingerConfig.Finger)+len(fingerConfig.Banner))
+}
+
+// diffFingerprints 统计新指纹库相对原有指纹库新增和移除的指纹数
+func diffFingerprints(previous *config.FingerprintConfig, next *config.FingerprintConfig) (int, int) {
+    keys := func(fingerConfig *config.FingerprintConfig) map[string]int {
+        counts := make(map[string]int)
+        if fingerConfig == nil {
+            return counts
+        }
+        for _, fp := range fingerConfig.Finger {
+            counts[config.FingerprintKey(fp)]++
+        }
+        for _, fp := range fingerConfig.Banner {
+            counts["banner\x01"+config.FingerprintKey(fp)]++
+        }
+        return counts
+    }
+    before, after := keys(previous), keys(next)
+    added, removed := 0, 0
+    for key, count := range after {
+        if count > before[key] {
+            added += count - before[key]
+        }
+    }
+    for key, count := range before {
+        if count > after[key] {
+            removed += count - after[key]
+        }
+    }
+    return added, removed
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "hfinger/config"
+)
+
+// setReloadPaths 将指纹文件路径指向 dir，并以 initial 作为当前指纹库，测试结束后恢复
+func setReloadPaths(t *testing.T, dir string, initial *config.FingerprintConfig) {
+    previousFinger, previousUser, previousBanner, previousExtra := config.Fingerfullpath, config.Userfullpath, config.Bannerfullpath, config.ExtraFingerpaths
+    previousConfig := config.Config
+    t.Cleanup(func() {
+        config.Fingerfullpath, config.Userfullpath, config.Bannerfullpath, config.ExtraFingerpaths = previousFinger, previousUser, previousBanner, previousExtra
+        config.Config = previousConfig
+        liveConfig.Store(nil)
+    })
+    config.Fingerfullpath = filepath.Join(dir, "finger.json")
+    config.Userfullpath = filepath.Join(dir, "custom")
+    config.Bannerfullpath = filepath.Join(dir, "banner.json")
+    config.ExtraFingerpaths = nil
+    config.Config = initial
+    liveConfig.Store(initial)
+}
+
+// TestReloadFingerprints 文件有效时替换当前指纹库，只有警告时照常加载，无法解析或校验出错时保留原有指纹库
+func TestReloadFingerprints(t *testing.T) {
+    initial, err := config.ParseFingerprintConfig([]byte(`{"finger": [
+        {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]}
+    ]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    dir := t.TempDir()
+    setReloadPaths(t, dir, initial)
+
+    tests := []struct {
+        name     string
+        content  string
+        reloaded bool
+    }{
+        {"valid", `{"finger": [
+            {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]},
+            {"cms": "Tomcat", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: Apache-Coyote"]}
+        ]}`, true},
+        {"invalid json", `{"finger": [`, false},
+        {"invalid regex", `{"finger": [{"cms": "Broken", "method": "regex", "location": "body", "logic": "or", "rule": ["a(b"]}]}`, false},
+        {"duplicate fingerprint", `{"finger": [
+            {"cms": "Nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]},
+            {"cms": "nginx", "method": "keyword", "location": "header", "logic": "or", "rule": ["Server: nginx"]}
+        ]}`, false},
+        {"warning only", `{"finger": [{"cms": "Short", "method": "keyword", "location": "body", "logic": "or", "rule": ["ab"]}]}`, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := os.WriteFile(config.Fingerfullpath, []byte(tt.content), 0644); err != nil {
+                t.Fatal(err)
+            }
+            previous := currentFingerprints()
+            reloadFingerprints()
+            current := currentFingerprints()
+            if reloaded := current != previous; reloaded != tt.reloaded {
+                t.Fatalf("reloaded = %v, want %v", reloaded, tt.reloaded)
+            }
+            if config.Config != initial {
+                t.Error("reload replaced config.Config")
+            }
+        })
+    }
+    if fingers := currentFingerprints().Finger; len(fingers) != 1 || fingers[0].CMS != "Short" {
+        t.Errorf("current fingerprints = %+v, want the last valid file", fingers)
+    }
+}
+
+// TestDiffFingerprints 新增和移除的指纹按影响匹配的字段统计，规则顺序和 CMS 大小写不同不计入
+func TestDiffFingerprints(t *testing.T) {
+    nginx := config.Fingerprint{CMS: "Nginx", Method: "keyword", Location: "header", Logic: "or", Rule: []string{"Server: nginx", "nginx/"}}
+    reordered := config.Fingerprint{CMS: "nginx", Method: "keyword", Location: "header", Logic: "or", Rule: []string{"nginx/", "Server: nginx"}}
+    tomcat := config.Fingerprint{CMS: "Tomcat", Method: "keyword", Location: "header", Logic: "or", Rule: []string{"Server: Apache-Coyote"}}
+    ssh := config.Fingerprint{CMS: "OpenSSH", Method: "keyword", Location: "banner", Logic: "or", Rule: []string{"SSH-2.0-OpenSSH"}}
+
+    previous := &config.FingerprintConfig{Finger: []config.Fingerprint{nginx, tomcat}}
+    next := &config.FingerprintConfig{Finger: []config.Fingerprint{reordered}, Banner: []config.Fingerprint{ssh}}
+    if added, removed := diffFingerprints(previous, next); added != 1 || removed != 1 {
+        t.Errorf("added %d, removed %d, want 1 and 1", added, removed)
+    }
+    if added, removed := diffFingerprints(nil, previous); added != 2 || removed != 0 {
+        t.Errorf("added %d, removed %d from an empty library, want 2 and 0", added, removed)
+    }
+}
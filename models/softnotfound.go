@@ -0,0 +1,160 @@
+package models
+
+import (
+    "fmt"
+    "hash/fnv"
+    "math/bits"
+    "strings"
+    "unicode"
+
+    "hfinger/config"
+    "hfinger/logger"
+)
+
+const (
+    // similarHashDistance 为两个页面 simhash 的最大汉明距离，不超过时视为同一页面
+    similarHashDistance = 3
+    // similarLengthRatio 为两个页面长度的最大相对差异
+    similarLengthRatio = 0.1
+)
+
+var (
+    honeypotThreshold   = 15 // 同一目标直接识别出的产品数达到该值时视为蜜罐
+    suppressSoft404     bool // 是否丢弃软404目标中未在首页识别出的结果
+    suppressSoft404Host bool // 是否丢弃软404目标的全部结果
+    suppressHoneypot    bool // 是否丢弃疑似蜜罐目标的全部结果
+)
+
+// SetHoneypotThreshold 设置判定为蜜罐的产品数，不大于0时不检查
+func SetHoneypotThreshold(threshold int) {
+    honeypotThreshold = threshold
+}
+
+// SetSuppress 设置不输出的可疑结果：soft404、soft404-host 和 honeypot
+func SetSuppress(kinds []string) error {
+    for _, kind := range kinds {
+        switch strings.ToLower(strings.TrimSpace(kind)) {
+        case "soft404":
+            suppressSoft404 = true
+        case "soft404-host":
+            suppressSoft404Host = true
+        case "honeypot":
+            suppressHoneypot = true
+        default:
+            return fmt.Errorf("unknown suppress option %q, expected soft404, soft404-host or honeypot", kind)
+        }
+    }
+    return nil
+}
+
+// pageSignature 为一次响应的摘要，用于比较随机路径和首页是否返回了同一页面
+type pageSignature struct {
+    URL        string // 重定向后的最终地址
+    StatusCode int
+    Length     int
+    Hash       uint64
+}
+
+func newPageSignature(url string, statusCode int, text []byte) pageSignature {
+    return pageSignature{URL: url, StatusCode: statusCode, Length: len(text), Hash: simhash(text)}
+}
+
+// isSoftNotFound 判断目标是否对不存在的路径返回与首页相同的页面
+// 随机路径重定向到首页，或状态码相同且长度和 simhash 相近时成立，随机路径返回错误状态码时不成立
+func isSoftNotFound(root pageSignature, random pageSignature) bool {
+    if root.StatusCode == 0 || random.StatusCode == 0 {
+        return false
+    }
+    if random.URL == root.URL {
+        return true
+    }
+    if random.StatusCode != root.StatusCode || random.StatusCode >= 400 {
+        return false
+    }
+    longer, shorter := root.Length, random.Length
+    if longer < shorter {
+        longer, shorter = shorter, longer
+    }
+    if longer > 0 && float64(longer-shorter)/float64(longer) > similarLengthRatio {
+        return false
+    }
+    return bits.OnesCount64(root.Hash^random.Hash) <= similarHashDistance
+}
+
+// simhash 计算页面内容的64位 simhash，内容相近的页面汉明距离较小
+// 以字母数字组成的词为特征，中文等连续文字按整段计算
+func simhash(text []byte) uint64 {
+    var weights [64]int
+    for _, token := range strings.FieldsFunc(string(text), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    }) {
+        h := fnv.New64a()
+        h.Write([]byte(token))
+        sum := h.Sum64()
+        for i := 0; i < 64; i++ {
+            if sum&(1<<uint(i)) != 0 {
+                weights[i]++
+            } else {
+                weights[i]--
+            }
+        }
+    }
+    var hash uint64
+    for i, weight := range weights {
+        if weight > 0 {
+            hash |= 1 << uint(i)
+        }
+    }
+    return hash
+}
+
+// isHoneypot 判断直接识别出的产品数是否多得不合常理
+func isHoneypot(count int) bool {
+    return honeypotThreshold > 0 && count >= honeypotThreshold
+}
+
+// resultFlags 返回附加在 [+] 行后的可疑标记，未在首页识别出的结果只标记 [soft-404]
+func resultFlags(result config.Result) string {
+    var flags string
+    if result.SoftNotFound {
+        flags += " [soft-404]"
+    } else if result.SoftNotFoundHost {
+        flags += " [soft-404-host]"
+    }
+    if result.Honeypot {
+        flags += " [honeypot]"
+    }
+    return flags
+}
+
+// flagSuspiciousResults 标记软404目标的全部结果及其中未在首页识别出的结果，以及疑似蜜罐目标的全部结果
+// matchedURLs 为每个产品被识别出的页面地址，首页重定向后的地址视为首页
+func flagSuspiciousResults(target string, results []config.Result, root pageSignature, random pageSignature, matchedURLs map[string][]string) {
+    if len(results) == 0 {
+        return
+    }
+    if isSoftNotFound(root, random) {
+        flagged := 0
+        for i := range results {
+            onRoot := false
+            for _, url := range matchedURLs[results[i].CMS] {
+                if url == root.URL {
+                    onRoot = true
+                    break
+                }
+            }
+            results[i].SoftNotFoundHost = true
+            if !onRoot {
+                results[i].SoftNotFound = true
+                flagged++
+            }
+        }
+        logger.Warn("[%s] Nonexistent paths return the same page as the root, %d of the %d results were not seen on the root page and may be false positives", target, flagged, len(results))
+    }
+    if isHoneypot(len(results)) {
+        logger.Warn("[%s] Matched %d products, the target is likely a honeypot", target, len(results))
+        for i := range results {
+            results[i].Honeypot = true
+        }
+    }
+}
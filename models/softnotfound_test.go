@@ -0,0 +1,132 @@
+package models
+
+import (
+    "math/bits"
+    "strings"
+    "testing"
+
+    "hfinger/config"
+)
+
+// TestSimhash 内容相近的页面汉明距离小，内容不同的页面汉明距离大
+func TestSimhash(t *testing.T) {
+    page := strings.Repeat("<div>Welcome to the demo portal, please sign in to continue.</div>", 20)
+    tests := []struct {
+        name    string
+        a, b    string
+        similar bool
+    }{
+        {"identical", page, page, true},
+        {"whitespace and punctuation", page, strings.ReplaceAll(strings.ReplaceAll(page, " ", "\n  "), ",", ";"), true},
+        {"random token", page + "<!-- request 9f8e7d6c -->", page + "<!-- request 0a1b2c3d -->", true},
+        {"chinese", "欢迎登录管理系统 " + page, "欢迎登录管理系统 " + page, true},
+        {"different page", page, strings.Repeat("<h1>404 Not Found</h1><p>The requested URL was not found on this server.</p>", 20), false},
+        {"empty and page", "", page, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            distance := bits.OnesCount64(simhash([]byte(tt.a)) ^ simhash([]byte(tt.b)))
+            if (distance <= similarHashDistance) != tt.similar {
+                t.Errorf("distance = %d, want similar %v", distance, tt.similar)
+            }
+        })
+    }
+    if simhash(nil) != 0 {
+        t.Error("simhash of empty content is not 0")
+    }
+}
+
+// TestIsSoftNotFound 随机路径重定向到首页或返回相近的页面时为软404，错误状态码和内容不同时不是
+func TestIsSoftNotFound(t *testing.T) {
+    page := []byte(strings.Repeat("<div>Welcome to the demo portal, please sign in to continue.</div>", 20))
+    notFound := []byte(strings.Repeat("<h1>404 Not Found</h1><p>The requested URL was not found.</p>", 20))
+    root := newPageSignature("http://example.com/", 200, page)
+    tests := []struct {
+        name   string
+        root   pageSignature
+        random pageSignature
+        want   bool
+    }{
+        {"same page", root, newPageSignature("http://example.com/abc123", 200, page), true},
+        {"redirect to root", root, newPageSignature("http://example.com/", 200, page), true},
+        {"redirect to root with error status", root, newPageSignature("http://example.com/", 404, notFound), true},
+        {"real 404", root, newPageSignature("http://example.com/abc123", 404, notFound), false},
+        {"same page with error status", newPageSignature("http://example.com/", 404, page), newPageSignature("http://example.com/abc123", 404, page), false},
+        {"different status", root, newPageSignature("http://example.com/abc123", 302, page), false},
+        {"different content", root, newPageSignature("http://example.com/abc123", 200, notFound), false},
+        {"length differs", root, newPageSignature("http://example.com/abc123", 200, append(append([]byte{}, page...), page...)), false},
+        {"root not requested", pageSignature{}, newPageSignature("http://example.com/abc123", 200, page), false},
+        {"random path failed", root, pageSignature{}, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := isSoftNotFound(tt.root, tt.random); got != tt.want {
+                t.Errorf("isSoftNotFound = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestFlagSuspiciousResults 软404目标的全部结果带有目标级标记，未在首页识别出的结果另有单条标记
+func TestFlagSuspiciousResults(t *testing.T) {
+    page := []byte("<title>Portal</title><div>Welcome to the demo portal</div>")
+    root := newPageSignature("http://example.com/", 200, page)
+    matchedURLs := map[string][]string{
+        "Nginx":  {"http://example.com/", "http://example.com/abc123"},
+        "Tomcat": {"http://example.com/abc123"},
+    }
+    newResults := func() []config.Result {
+        return []config.Result{{CMS: "Nginx"}, {CMS: "Tomcat"}}
+    }
+
+    results := newResults()
+    flagSuspiciousResults("http://example.com", results, root, newPageSignature("http://example.com/abc123", 404, []byte("Not Found")), matchedURLs)
+    for _, result := range results {
+        if result.SoftNotFound || result.SoftNotFoundHost {
+            t.Errorf("%s flagged on a target that returns 404", result.CMS)
+        }
+    }
+
+    results = newResults()
+    flagSuspiciousResults("http://example.com", results, root, newPageSignature("http://example.com/abc123", 200, page), matchedURLs)
+    if !results[0].SoftNotFoundHost || results[0].SoftNotFound {
+        t.Errorf("Nginx = %+v, want only the host flag", results[0])
+    }
+    if !results[1].SoftNotFoundHost || !results[1].SoftNotFound {
+        t.Errorf("Tomcat = %+v, want both flags", results[1])
+    }
+    if flags := resultFlags(results[0]) + resultFlags(results[1]); flags != " [soft-404-host] [soft-404]" {
+        t.Errorf("flags = %q", flags)
+    }
+}
+
+// TestSuppressSoftNotFound --suppress 的 soft404 只丢弃未在首页识别出的结果，soft404-host 丢弃软404目标的全部结果
+func TestSuppressSoftNotFound(t *testing.T) {
+    t.Cleanup(func() { suppressSoft404, suppressSoft404Host, suppressHoneypot = false, false, false })
+    onRoot := config.Result{CMS: "Nginx", SoftNotFoundHost: true}
+    offRoot := config.Result{CMS: "Tomcat", SoftNotFoundHost: true, SoftNotFound: true}
+    normal := config.Result{CMS: "Apache"}
+    tests := []struct {
+        kinds []string
+        want  []bool
+    }{
+        {nil, []bool{true, true, true}},
+        {[]string{"soft404"}, []bool{true, false, true}},
+        {[]string{" Soft404-Host "}, []bool{false, false, true}},
+        {[]string{"honeypot"}, []bool{true, true, true}},
+    }
+    for _, tt := range tests {
+        suppressSoft404, suppressSoft404Host, suppressHoneypot = false, false, false
+        if err := SetSuppress(tt.kinds); err != nil {
+            t.Fatal(err)
+        }
+        for i, result := range []config.Result{onRoot, offRoot, normal} {
+            if got := resultAllowed(result); got != tt.want[i] {
+                t.Errorf("suppress %v: %s allowed = %v, want %v", tt.kinds, result.CMS, got, tt.want[i])
+            }
+        }
+    }
+    if err := SetSuppress([]string{"soft-404"}); err == nil {
+        t.Error("expected an error for an unknown suppress option")
+    }
+}
@@ -0,0 +1,334 @@
+package models
+
+import (
+    "bytes"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "hfinger/logger"
+)
+
+var (
+    targetPorts        []int              // --ports 指定的端口，为空时未带端口的主机使用协议默认端口
+    targetScheme       = "auto"           // 未带协议的目标使用的协议
+    schemeProbeTimeout = 3 * time.Second  // 探测端口协议时建立连接和 TLS 握手的超时时间
+)
+
+// TargetSchemes 为未带协议的目标可选的协议：auto 探测端口实际使用的协议，both 同时识别 https 和 http
+var TargetSchemes = []string{"auto", "https", "http", "both"}
+
+// targetSpec 为一行目标解析后的结果，IP 范围和 CIDR 在识别时才逐个展开
+type targetSpec struct {
+    url   string // 已带协议的目标，原样识别
+    name  string // 域名或单个 IP
+    first net.IP // IP 范围的起止地址，包含两端
+    last  net.IP
+    ports []int // 目标中指定的端口，为空时使用 --ports
+}
+
+// scanTarget 为展开后的单个目标，url 为空时识别前需要探测 host:port 使用的协议
+type scanTarget struct {
+    url   string
+    host  string
+    port  int  // 为 0 时使用协议默认端口
+    sweep bool // 是否由 IP 范围或多个端口展开，端口未开放时不报错
+}
+
+// SetTargetOptions 设置未带协议的目标展开时使用的端口列表和协议，使用代理时不直连探测协议，auto 按 both 处理
+func SetTargetOptions(ports string, scheme string, proxied bool) error {
+    parsed, err := ParsePorts(ports)
+    if err != nil {
+        return err
+    }
+    scheme = strings.ToLower(strings.TrimSpace(scheme))
+    if !containsScheme(scheme) {
+        return fmt.Errorf("unknown scheme %q, expected one of %s", scheme, strings.Join(TargetSchemes, ", "))
+    }
+    if scheme == "auto" && proxied {
+        scheme = "both"
+    }
+    targetPorts, targetScheme = parsed, scheme
+    return nil
+}
+
+func containsScheme(scheme string) bool {
+    for _, s := range TargetSchemes {
+        if s == scheme {
+            return true
+        }
+    }
+    return false
+}
+
+// ParsePorts 解析逗号分隔的端口和端口范围，例如 80,443,8000-8100，重复的端口只保留第一次出现的位置
+func ParsePorts(spec string) ([]int, error) {
+    var ports []int
+    seen := make(map[int]bool)
+    for _, item := range strings.Split(spec, ",") {
+        item = strings.TrimSpace(item)
+        if item == "" {
+            continue
+        }
+        low, high, isRange := strings.Cut(item, "-")
+        start, err := parsePort(low)
+        if err != nil {
+            return nil, err
+        }
+        end := start
+        if isRange {
+            if end, err = parsePort(high); err != nil {
+                return nil, err
+            }
+            if end < start {
+                return nil, fmt.Errorf("invalid port range %q", item)
+            }
+        }
+        for port := start; port <= end; port++ {
+            if !seen[port] {
+                seen[port] = true
+                ports = append(ports, port)
+            }
+        }
+    }
+    return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+    port, err := strconv.Atoi(strings.TrimSpace(s))
+    if err != nil || port < 1 || port > 65535 {
+        return 0, fmt.Errorf("invalid port %q, expected 1 to 65535", s)
+    }
+    return port, nil
+}
+
+// CheckTarget 检查 -u 指定的目标能否解析
+func CheckTarget(target string) error {
+    _, err := parseTarget(target)
+    return err
+}
+
+// parseTarget 解析一行目标：带协议的 URL、域名、IP、host:port、CIDR 和 IP 范围，后四种可以带 :port
+func parseTarget(target string) (targetSpec, error) {
+    if strings.Contains(target, "://") {
+        return targetSpec{url: target}, nil
+    }
+
+    host := strings.TrimSuffix(target, "/")
+    var spec targetSpec
+    if h, p, err := net.SplitHostPort(host); err == nil {
+        port, err := parsePort(p)
+        if err != nil {
+            return spec, err
+        }
+        host, spec.ports = h, []int{port}
+    }
+    host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+    switch {
+    case strings.Contains(host, "/"):
+        _, network, err := net.ParseCIDR(host)
+        if err != nil {
+            return spec, fmt.Errorf("invalid CIDR %q", host)
+        }
+        spec.first, spec.last = network.IP, lastIP(network)
+    case strings.Contains(host, "-") && net.ParseIP(host[:strings.Index(host, "-")]) != nil:
+        first, last, err := parseIPRange(host)
+        if err != nil {
+            return spec, err
+        }
+        spec.first, spec.last = first, last
+    case net.ParseIP(host) != nil || validHostname(host):
+        spec.name = host
+    default:
+        return spec, fmt.Errorf("invalid host %q", host)
+    }
+
+    if bannerMode && len(spec.ports) == 0 && len(targetPorts) == 0 {
+        return spec, errors.New("banner mode requires a port, use host:port or --ports")
+    }
+    return spec, nil
+}
+
+// parseIPRange 解析 10.0.0.1-10.0.0.50 和 10.0.0.1-50 形式的 IP 范围，后者只替换 IPv4 地址的最后一段
+func parseIPRange(s string) (net.IP, net.IP, error) {
+    low, high, _ := strings.Cut(s, "-")
+    first := normalizeIP(net.ParseIP(low))
+    last := normalizeIP(net.ParseIP(high))
+    if last == nil {
+        n, err := strconv.Atoi(high)
+        if err != nil || n < 0 || n > 255 || len(first) != net.IPv4len {
+            return nil, nil, fmt.Errorf("invalid IP range %q", s)
+        }
+        last = append(net.IP(nil), first...)
+        last[3] = byte(n)
+    }
+    if len(first) != len(last) || bytes.Compare(first, last) > 0 {
+        return nil, nil, fmt.Errorf("invalid IP range %q", s)
+    }
+    return first, last, nil
+}
+
+// normalizeIP 将 IPv4 地址转为 4 字节形式，便于和 CIDR 的地址比较
+func normalizeIP(ip net.IP) net.IP {
+    if v4 := ip.To4(); v4 != nil {
+        return v4
+    }
+    return ip
+}
+
+func lastIP(network *net.IPNet) net.IP {
+    last := make(net.IP, len(network.IP))
+    for i := range network.IP {
+        last[i] = network.IP[i] | ^network.Mask[i]
+    }
+    return last
+}
+
+func nextIP(ip net.IP) net.IP {
+    next := append(net.IP(nil), ip...)
+    for i := len(next) - 1; i >= 0; i-- {
+        next[i]++
+        if next[i] != 0 {
+            break
+        }
+    }
+    return next
+}
+
+// validHostname 判断是否为由字母、数字、-、_ 和 . 组成的主机名
+func validHostname(host string) bool {
+    if host == "" || len(host) > 253 {
+        return false
+    }
+    for _, r := range host {
+        if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+            return false
+        }
+    }
+    return true
+}
+
+// each 依次展开目标，每次只生成一个地址，/16 等大范围不会预先分配全部目标
+func (spec targetSpec) each(yield func(scanTarget)) {
+    if spec.url != "" {
+        yield(scanTarget{url: spec.url})
+        return
+    }
+    ports := spec.ports
+    if len(ports) == 0 {
+        ports = targetPorts
+    }
+    if len(ports) == 0 {
+        ports = []int{0}
+    }
+    sweep := spec.name == "" || len(ports) > 1
+    if spec.name != "" {
+        for _, port := range ports {
+            expandScheme(spec.name, port, sweep, yield)
+        }
+        return
+    }
+    for ip := spec.first; ; ip = nextIP(ip) {
+        for _, port := range ports {
+            expandScheme(ip.String(), port, sweep, yield)
+        }
+        if ip.Equal(spec.last) {
+            return
+        }
+    }
+}
+
+// expandScheme 根据 banner 模式和 --scheme 为 host:port 生成目标，auto 时留给识别时探测
+func expandScheme(host string, port int, sweep bool, yield func(scanTarget)) {
+    address := host
+    if strings.Contains(host, ":") {
+        address = "[" + host + "]"
+    }
+    if port != 0 {
+        address = net.JoinHostPort(host, strconv.Itoa(port))
+    }
+    switch {
+    case bannerMode:
+        yield(scanTarget{url: "tcp://" + address})
+    case targetScheme == "auto":
+        yield(scanTarget{host: host, port: port, sweep: sweep})
+    case targetScheme == "both":
+        yield(scanTarget{url: "https://" + address})
+        yield(scanTarget{url: "http://" + address})
+    default:
+        yield(scanTarget{url: targetScheme + "://" + address})
+    }
+}
+
+// processScanTarget 识别展开后的目标，未带协议时先探测端口使用的协议
+// 未指定端口时先尝试 443 端口的 https，不是 TLS 时使用 http；端口返回非 HTTP 的欢迎信息时按 TCP 服务识别
+func processScanTarget(target scanTarget) {
+    if target.url != "" {
+        ProcessTarget(target.url)
+        return
+    }
+    port := target.port
+    if port == 0 {
+        port = 443
+    }
+    address := net.JoinHostPort(target.host, strconv.Itoa(port))
+    scheme, err := probeScheme(target.host, address)
+    if target.port == 0 {
+        address = target.host
+        if strings.Contains(address, ":") {
+            address = "[" + address + "]"
+        }
+        if scheme != "https" {
+            // 443 端口不可用或不是 TLS 时使用 http 默认端口
+            scheme, err = "http", nil
+        }
+    }
+    if err != nil {
+        if !target.sweep {
+            logger.PrintByLevel(err, "tcp://"+address)
+        }
+        return
+    }
+    ProcessTarget(scheme + "://" + address)
+}
+
+// probeScheme 连接端口并发起 TLS 握手，判断端口使用 https、http 还是其它 TCP 协议
+// 握手成功或服务返回 TLS 协议的错误时为 https；返回 HTTP 响应或 HTML 时为 http，返回其它内容时为 tcp；
+// 服务未返回内容就关闭连接或超时时无法判断，按 http 处理
+func probeScheme(host string, address string) (string, error) {
+    conn, err := net.DialTimeout("tcp", address, schemeProbeTimeout)
+    if err != nil {
+        return "", err
+    }
+    defer conn.Close()
+    conn.SetDeadline(time.Now().Add(schemeProbeTimeout))
+
+    tlsConfig := &tls.Config{InsecureSkipVerify: true}
+    if net.ParseIP(host) == nil {
+        tlsConfig.ServerName = host
+    }
+    err = tls.Client(conn, tlsConfig).Handshake()
+    if err == nil {
+        return "https", nil
+    }
+    var recordErr tls.RecordHeaderError
+    if errors.As(err, &recordErr) {
+        // 部分 HTTP 服务对无法解析的请求按 HTTP/0.9 只返回 HTML 错误页，没有状态行
+        if header := recordErr.RecordHeader[:]; bytes.HasPrefix(header, []byte("HTTP/")) || header[0] == '<' {
+            return "http", nil
+        }
+        return "tcp", nil
+    }
+    var netErr net.Error
+    if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.As(err, &netErr) && netErr.Timeout() {
+        return "http", nil
+    }
+    return "https", nil
+}
@@ -0,0 +1,259 @@
+package models
+
+import (
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// setTargetOptions 设置目标展开选项，测试结束后恢复
+func setTargetOptions(t *testing.T, ports string, scheme string, banner bool) {
+    previousPorts, previousScheme, previousBanner := targetPorts, targetScheme, bannerMode
+    t.Cleanup(func() { targetPorts, targetScheme, bannerMode = previousPorts, previousScheme, previousBanner })
+    if err := SetTargetOptions(ports, scheme, false); err != nil {
+        t.Fatal(err)
+    }
+    bannerMode = banner
+}
+
+// TestParsePorts 端口和端口范围按出现顺序去重，越界和倒序的范围报错
+func TestParsePorts(t *testing.T) {
+    tests := []struct {
+        spec  string
+        ports []int
+        err   bool
+    }{
+        {"", nil, false},
+        {"80", []int{80}, false},
+        {"80,443", []int{80, 443}, false},
+        {" 8000-8003 , 80", []int{8000, 8001, 8002, 8003, 80}, false},
+        {"443,80,443,79-81", []int{443, 80, 79, 81}, false},
+        {"1,65535", []int{1, 65535}, false},
+        {"80,,443,", []int{80, 443}, false},
+        {"0", nil, true},
+        {"65536", nil, true},
+        {"http", nil, true},
+        {"8100-8000", nil, true},
+        {"80-", nil, true},
+        {"-80", nil, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.spec, func(t *testing.T) {
+            ports, err := ParsePorts(tt.spec)
+            if (err != nil) != tt.err {
+                t.Fatalf("error = %v, want error %v", err, tt.err)
+            }
+            if !reflect.DeepEqual(ports, tt.ports) {
+                t.Errorf("ports = %v, want %v", ports, tt.ports)
+            }
+        })
+    }
+}
+
+// TestParseIPRange 支持完整的起止地址和只写 IPv4 最后一段的结束地址，倒序和混用地址族报错
+func TestParseIPRange(t *testing.T) {
+    tests := []struct {
+        spec        string
+        first, last string
+        err         bool
+    }{
+        {"10.0.0.1-10.0.0.50", "10.0.0.1", "10.0.0.50", false},
+        {"10.0.0.1-50", "10.0.0.1", "10.0.0.50", false},
+        {"10.0.0.7-7", "10.0.0.7", "10.0.0.7", false},
+        {"10.0.0.250-10.0.1.3", "10.0.0.250", "10.0.1.3", false},
+        {"2001:db8::1-2001:db8::ff", "2001:db8::1", "2001:db8::ff", false},
+        {"10.0.0.50-10.0.0.1", "", "", true},
+        {"10.0.0.50-1", "", "", true},
+        {"10.0.0.1-256", "", "", true},
+        {"10.0.0.1-abc", "", "", true},
+        {"10.0.0.1-2001:db8::1", "", "", true},
+        {"2001:db8::1-ff", "", "", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.spec, func(t *testing.T) {
+            first, last, err := parseIPRange(tt.spec)
+            if (err != nil) != tt.err {
+                t.Fatalf("error = %v, want error %v", err, tt.err)
+            }
+            if err == nil && (first.String() != tt.first || last.String() != tt.last) {
+                t.Errorf("range = %s-%s, want %s-%s", first, last, tt.first, tt.last)
+            }
+        })
+    }
+}
+
+// expandTarget 解析并展开一行目标，返回展开后的目标
+func expandTarget(target string) ([]scanTarget, error) {
+    spec, err := parseTarget(target)
+    if err != nil {
+        return nil, err
+    }
+    var targets []scanTarget
+    spec.each(func(target scanTarget) { targets = append(targets, target) })
+    return targets, nil
+}
+
+// TestParseTarget 各种目标写法按 --ports 和 --scheme 展开，单个目标不按扫描处理
+func TestParseTarget(t *testing.T) {
+    tests := []struct {
+        name   string
+        target string
+        ports  string
+        scheme string
+        banner bool
+        want   []scanTarget
+        err    string
+    }{
+        {name: "url", target: "https://example.com/login", scheme: "auto",
+            want: []scanTarget{{url: "https://example.com/login"}}},
+        {name: "url ignores ports", target: "http://example.com", ports: "80,443", scheme: "auto",
+            want: []scanTarget{{url: "http://example.com"}}},
+        {name: "hostname", target: "example.com", scheme: "auto",
+            want: []scanTarget{{host: "example.com"}}},
+        {name: "hostname with slash", target: "example.com/", scheme: "https",
+            want: []scanTarget{{url: "https://example.com"}}},
+        {name: "host and port", target: "10.0.0.1:8443", scheme: "auto",
+            want: []scanTarget{{host: "10.0.0.1", port: 8443}}},
+        {name: "host and port overrides ports", target: "10.0.0.1:8443", ports: "80,443", scheme: "http",
+            want: []scanTarget{{url: "http://10.0.0.1:8443"}}},
+        {name: "ports", target: "example.com", ports: "80,8080", scheme: "auto",
+            want: []scanTarget{{host: "example.com", port: 80, sweep: true}, {host: "example.com", port: 8080, sweep: true}}},
+        {name: "both schemes", target: "example.com", scheme: "both",
+            want: []scanTarget{{url: "https://example.com"}, {url: "http://example.com"}}},
+        {name: "ipv6", target: "[2001:db8::1]:8080", scheme: "http",
+            want: []scanTarget{{url: "http://[2001:db8::1]:8080"}}},
+        {name: "ipv6 without port", target: "2001:db8::1", scheme: "https",
+            want: []scanTarget{{url: "https://[2001:db8::1]"}}},
+        {name: "cidr", target: "192.168.1.0/30", scheme: "auto",
+            want: []scanTarget{{host: "192.168.1.0", sweep: true}, {host: "192.168.1.1", sweep: true}, {host: "192.168.1.2", sweep: true}, {host: "192.168.1.3", sweep: true}}},
+        {name: "cidr host bits", target: "192.168.1.5/31:8080", scheme: "http",
+            want: []scanTarget{{url: "http://192.168.1.4:8080"}, {url: "http://192.168.1.5:8080"}}},
+        {name: "range", target: "10.0.0.255-10.0.1.0", ports: "80", scheme: "auto",
+            want: []scanTarget{{host: "10.0.0.255", port: 80, sweep: true}, {host: "10.0.1.0", port: 80, sweep: true}}},
+        {name: "short range with port", target: "10.0.0.1-2:22", scheme: "auto", banner: true,
+            want: []scanTarget{{url: "tcp://10.0.0.1:22"}, {url: "tcp://10.0.0.2:22"}}},
+        {name: "banner with ports", target: "example.com", ports: "22", scheme: "auto", banner: true,
+            want: []scanTarget{{url: "tcp://example.com:22"}}},
+        {name: "hostname with dash", target: "my-host.example.com", scheme: "http",
+            want: []scanTarget{{url: "http://my-host.example.com"}}},
+        {name: "banner without port", target: "example.com", scheme: "auto", banner: true, err: "banner mode requires a port"},
+        {name: "invalid port", target: "example.com:99999", scheme: "auto", err: "invalid port"},
+        {name: "invalid cidr", target: "10.0.0.0/33", scheme: "auto", err: "invalid CIDR"},
+        {name: "invalid range", target: "10.0.0.9-1", scheme: "auto", err: "invalid IP range"},
+        {name: "invalid host", target: "exa mple.com", scheme: "auto", err: "invalid host"},
+        {name: "empty host", target: ":80", scheme: "auto", err: "invalid host"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            setTargetOptions(t, tt.ports, tt.scheme, tt.banner)
+            targets, err := expandTarget(tt.target)
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(targets, tt.want) {
+                t.Errorf("targets = %+v, want %+v", targets, tt.want)
+            }
+        })
+    }
+}
+
+// TestSetTargetOptions 未知协议报错，使用代理时 auto 按 both 处理
+func TestSetTargetOptions(t *testing.T) {
+    setTargetOptions(t, "", "auto", false)
+    if err := SetTargetOptions("", "ftp", false); err == nil {
+        t.Error("expected an error for an unknown scheme")
+    }
+    if err := SetTargetOptions("80", " AUTO ", true); err != nil {
+        t.Fatal(err)
+    }
+    if targetScheme != "both" || !reflect.DeepEqual(targetPorts, []int{80}) {
+        t.Errorf("scheme = %s, ports = %v, want both and [80]", targetScheme, targetPorts)
+    }
+}
+
+// startTCPServer 在本地端口启动 TCP 服务，每个连接交给 handle 处理
+func startTCPServer(t *testing.T, handle func(net.Conn)) string {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer conn.Close()
+                handle(conn)
+            }()
+        }
+    }()
+    return listener.Addr().String()
+}
+
+// TestProbeScheme 根据 TLS 握手的结果判断端口使用 https、http 还是其它 TCP 协议
+func TestProbeScheme(t *testing.T) {
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+    tlsServer := httptest.NewTLSServer(handler)
+    defer tlsServer.Close()
+    plainServer := httptest.NewServer(handler)
+    defer plainServer.Close()
+
+    timeout := schemeProbeTimeout
+    schemeProbeTimeout = 300 * time.Millisecond
+    t.Cleanup(func() { schemeProbeTimeout = timeout })
+
+    tests := []struct {
+        name    string
+        address string
+        want    string
+    }{
+        {"https", tlsServer.Listener.Addr().String(), "https"},
+        {"http", plainServer.Listener.Addr().String(), "http"},
+        {"html without status line", startTCPServer(t, func(conn net.Conn) {
+            conn.Read(make([]byte, 1024))
+            conn.Write([]byte("<html><body>Bad Request</body></html>"))
+        }), "http"},
+        {"ssh greeting", startTCPServer(t, func(conn net.Conn) {
+            conn.Write([]byte("SSH-2.0-OpenSSH_8.9p1\r\n"))
+            conn.Read(make([]byte, 1024))
+        }), "tcp"},
+        {"silent", startTCPServer(t, func(conn net.Conn) {
+            time.Sleep(time.Second)
+        }), "http"},
+        {"closed", startTCPServer(t, func(conn net.Conn) {}), "http"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            scheme, err := probeScheme("127.0.0.1", tt.address)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if scheme != tt.want {
+                t.Errorf("scheme = %s, want %s", scheme, tt.want)
+            }
+        })
+    }
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    address := listener.Addr().String()
+    listener.Close()
+    if _, err := probeScheme("127.0.0.1", address); err == nil {
+        t.Error("expected an error for a closed port")
+    }
+}
@@ -37,9 +37,34 @@ func WriteXLSXOutput(filename string, results []config.Result) error {
     header := summarySheet.AddRow()
     header.AddCell().Value = "URL"
     header.AddCell().Value = "CMS"
+    header.AddCell().Value = "Version"
     header.AddCell().Value = "Server"
     header.AddCell().Value = "StatusCode"
     header.AddCell().Value = "Title"
+    header.AddCell().Value = "Banner"
+    header.AddCell().Value = "IconURL"
+    header.AddCell().Value = "IconHash"
+    header.AddCell().Value = "CertSubject"
+    header.AddCell().Value = "CertIssuer"
+    header.AddCell().Value = "CertSANs"
+    header.AddCell().Value = "CertSerial"
+    header.AddCell().Value = "CertSHA256"
+    header.AddCell().Value = "GMTLS"
+    header.AddCell().Value = "FingerprintID"
+    header.AddCell().Value = "MatchMethod"
+    header.AddCell().Value = "MatchLocation"
+    header.AddCell().Value = "MatchedRules"
+    header.AddCell().Value = "Evidence"
+    header.AddCell().Value = "Category"
+    header.AddCell().Value = "Tags"
+    header.AddCell().Value = "Vendor"
+    header.AddCell().Value = "Severity"
+    header.AddCell().Value = "Implied"
+    header.AddCell().Value = "ImpliedBy"
+    header.AddCell().Value = "Confidence"
+    header.AddCell().Value = "SoftNotFound"
+    header.AddCell().Value = "SoftNotFoundHost"
+    header.AddCell().Value = "Honeypot"
 
     // 创建一个 map，用于按 CMS 分类存储结果
     cmsSheets := make(map[string]*xlsx.Sheet)
@@ -49,9 +74,34 @@ func WriteXLSXOutput(filename string, results []config.Result) error {
         row := summarySheet.AddRow()
         row.AddCell().Value = result.URL
         row.AddCell().Value = result.CMS
+        row.AddCell().Value = result.Version
         row.AddCell().Value = result.Server
         row.AddCell().Value = strconv.Itoa(result.StatusCode)
         row.AddCell().Value = result.Title
+        row.AddCell().Value = result.Banner
+        row.AddCell().Value = result.IconURL
+        row.AddCell().Value = result.IconHash
+        row.AddCell().Value = result.CertSubject
+        row.AddCell().Value = result.CertIssuer
+        row.AddCell().Value = strings.Join(result.CertSANs, ", ")
+        row.AddCell().Value = result.CertSerial
+        row.AddCell().Value = result.CertSHA256
+        row.AddCell().Value = strconv.FormatBool(result.GMTLS)
+        row.AddCell().Value = result.FingerprintID
+        row.AddCell().Value = result.MatchMethod
+        row.AddCell().Value = result.MatchLocation
+        row.AddCell().Value = strings.Join(result.MatchedRules, ", ")
+        row.AddCell().Value = result.Evidence
+        row.AddCell().Value = result.Category
+        row.AddCell().Value = strings.Join(result.Tags, ", ")
+        row.AddCell().Value = result.Vendor
+        row.AddCell().Value = result.Severity
+        row.AddCell().Value = strconv.FormatBool(result.Implied)
+        row.AddCell().Value = result.ImpliedBy
+        row.AddCell().Value = strconv.Itoa(result.Confidence)
+        row.AddCell().Value = strconv.FormatBool(result.SoftNotFound)
+        row.AddCell().Value = strconv.FormatBool(result.SoftNotFoundHost)
+        row.AddCell().Value = strconv.FormatBool(result.Honeypot)
 
         // 按 CMS 创建新 sheet，并添加记录
         if _, exists := cmsSheets[result.CMS]; !exists {
@@ -65,17 +115,67 @@ func WriteXLSXOutput(filename string, results []config.Result) error {
             // 为新 sheet 添加表头
             cmsHeader := cmsSheet.AddRow()
             cmsHeader.AddCell().Value = "URL"
+            cmsHeader.AddCell().Value = "Version"
             cmsHeader.AddCell().Value = "Server"
             cmsHeader.AddCell().Value = "StatusCode"
             cmsHeader.AddCell().Value = "Title"
+            cmsHeader.AddCell().Value = "Banner"
+            cmsHeader.AddCell().Value = "IconURL"
+            cmsHeader.AddCell().Value = "IconHash"
+            cmsHeader.AddCell().Value = "CertSubject"
+            cmsHeader.AddCell().Value = "CertIssuer"
+            cmsHeader.AddCell().Value = "CertSANs"
+            cmsHeader.AddCell().Value = "CertSerial"
+            cmsHeader.AddCell().Value = "CertSHA256"
+            cmsHeader.AddCell().Value = "GMTLS"
+            cmsHeader.AddCell().Value = "FingerprintID"
+            cmsHeader.AddCell().Value = "MatchMethod"
+            cmsHeader.AddCell().Value = "MatchLocation"
+            cmsHeader.AddCell().Value = "MatchedRules"
+            cmsHeader.AddCell().Value = "Evidence"
+            cmsHeader.AddCell().Value = "Category"
+            cmsHeader.AddCell().Value = "Tags"
+            cmsHeader.AddCell().Value = "Vendor"
+            cmsHeader.AddCell().Value = "Severity"
+            cmsHeader.AddCell().Value = "Implied"
+            cmsHeader.AddCell().Value = "ImpliedBy"
+            cmsHeader.AddCell().Value = "Confidence"
+            cmsHeader.AddCell().Value = "SoftNotFound"
+            cmsHeader.AddCell().Value = "SoftNotFoundHost"
+            cmsHeader.AddCell().Value = "Honeypot"
         }
 
         // 添加到 CMS 分类表
         cmsRow := cmsSheets[result.CMS].AddRow()
         cmsRow.AddCell().Value = result.URL
+        cmsRow.AddCell().Value = result.Version
         cmsRow.AddCell().Value = result.Server
         cmsRow.AddCell().Value = strconv.Itoa(result.StatusCode)
         cmsRow.AddCell().Value = result.Title
+        cmsRow.AddCell().Value = result.Banner
+        cmsRow.AddCell().Value = result.IconURL
+        cmsRow.AddCell().Value = result.IconHash
+        cmsRow.AddCell().Value = result.CertSubject
+        cmsRow.AddCell().Value = result.CertIssuer
+        cmsRow.AddCell().Value = strings.Join(result.CertSANs, ", ")
+        cmsRow.AddCell().Value = result.CertSerial
+        cmsRow.AddCell().Value = result.CertSHA256
+        cmsRow.AddCell().Value = strconv.FormatBool(result.GMTLS)
+        cmsRow.AddCell().Value = result.FingerprintID
+        cmsRow.AddCell().Value = result.MatchMethod
+        cmsRow.AddCell().Value = result.MatchLocation
+        cmsRow.AddCell().Value = strings.Join(result.MatchedRules, ", ")
+        cmsRow.AddCell().Value = result.Evidence
+        cmsRow.AddCell().Value = result.Category
+        cmsRow.AddCell().Value = strings.Join(result.Tags, ", ")
+        cmsRow.AddCell().Value = result.Vendor
+        cmsRow.AddCell().Value = result.Severity
+        cmsRow.AddCell().Value = strconv.FormatBool(result.Implied)
+        cmsRow.AddCell().Value = result.ImpliedBy
+        cmsRow.AddCell().Value = strconv.Itoa(result.Confidence)
+        cmsRow.AddCell().Value = strconv.FormatBool(result.SoftNotFound)
+        cmsRow.AddCell().Value = strconv.FormatBool(result.SoftNotFoundHost)
+        cmsRow.AddCell().Value = strconv.FormatBool(result.Honeypot)
     }
 
     return file.Save(filename)
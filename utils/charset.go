@@ -0,0 +1,75 @@
+package utils
+
+import (
+    "bytes"
+    "regexp"
+    "strings"
+    "unicode/utf8"
+
+    "golang.org/x/net/html/charset"
+    "golang.org/x/text/encoding"
+    "golang.org/x/text/encoding/simplifiedchinese"
+    "golang.org/x/text/encoding/traditionalchinese"
+)
+
+// metaCharsetPattern 匹配 <meta charset="gbk"> 和 <meta http-equiv="Content-Type" content="text/html; charset=gbk">
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+// metaSniffSize 为查找 meta 声明的字节数，与浏览器的预扫描范围一致
+const metaSniffSize = 1024
+
+// sniffEncodings 为未声明编码且不是有效 UTF-8 时依次尝试的编码，GB18030 兼容 GBK 和 GB2312
+var sniffEncodings = []encoding.Encoding{simplifiedchinese.GB18030, traditionalchinese.Big5}
+
+// DecodeToUTF8 将响应体转换为 UTF-8，编码依次取自 Content-Type、BOM、<meta> 声明和内容探测
+// 非文本内容、已是 UTF-8 或无法识别编码时原样返回
+func DecodeToUTF8(body []byte, contentType string) []byte {
+    if len(body) == 0 || !isTextContentType(contentType) {
+        return body
+    }
+    if enc, name, certain := charset.DetermineEncoding(body, contentType); certain {
+        return decodeWith(body, enc, name)
+    }
+
+    head := body
+    if len(head) > metaSniffSize {
+        head = head[:metaSniffSize]
+    }
+    if m := metaCharsetPattern.FindSubmatch(head); m != nil {
+        if enc, name := charset.Lookup(string(m[1])); enc != nil {
+            return decodeWith(body, enc, name)
+        }
+    }
+
+    if utf8.Valid(body) {
+        return body
+    }
+    for _, enc := range sniffEncodings {
+        if decoded, err := enc.NewDecoder().Bytes(body); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+            return decoded
+        }
+    }
+    return body
+}
+
+func decodeWith(body []byte, enc encoding.Encoding, name string) []byte {
+    if name == "utf-8" {
+        return body
+    }
+    decoded, err := enc.NewDecoder().Bytes(body)
+    if err != nil {
+        return body
+    }
+    return decoded
+}
+
+// isTextContentType 判断响应是否为需要转码的文本，未返回 Content-Type 时按文本处理
+func isTextContentType(contentType string) bool {
+    contentType = strings.ToLower(contentType)
+    return contentType == "" ||
+        strings.Contains(contentType, "text") ||
+        strings.Contains(contentType, "html") ||
+        strings.Contains(contentType, "xml") ||
+        strings.Contains(contentType, "json") ||
+        strings.Contains(contentType, "javascript")
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+    "testing"
+
+    "golang.org/x/text/encoding"
+    "golang.org/x/text/encoding/simplifiedchinese"
+    "golang.org/x/text/encoding/traditionalchinese"
+)
+
+// encodeText 使用 enc 编码 text，用于构造非 UTF-8 的响应体
+func encodeText(t *testing.T, enc encoding.Encoding, text string) []byte {
+    data, err := enc.NewEncoder().Bytes([]byte(text))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return data
+}
+
+// TestDecodeToUTF8 GBK、GB18030 和 Big5 的响应按 Content-Type、<meta> 声明或内容探测转换为 UTF-8，UTF-8 和非文本内容原样返回
+func TestDecodeToUTF8(t *testing.T) {
+    page := "<html><head><title>统一身份认证平台</title></head></html>"
+    gbkMeta := `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gb2312"><title>统一身份认证平台</title></head></html>`
+    big5Meta := `<html><head><meta charset="big5"><title>統一身份認證平臺</title></head></html>`
+    gbk := encodeText(t, simplifiedchinese.GBK, page)
+    tests := []struct {
+        name        string
+        body        []byte
+        contentType string
+        want        string
+    }{
+        {"gbk in content type", gbk, "text/html; charset=GBK", page},
+        {"gb18030 in content type", encodeText(t, simplifiedchinese.GB18030, page+"㐀"), "text/html;charset=gb18030", page + "㐀"},
+        {"gb2312 in meta", encodeText(t, simplifiedchinese.GBK, gbkMeta), "text/html", gbkMeta},
+        {"big5 in meta", encodeText(t, traditionalchinese.Big5, big5Meta), "", big5Meta},
+        {"big5 in content type", encodeText(t, traditionalchinese.Big5, "<title>統一身份認證平臺</title>"), "text/html; charset=big5", "<title>統一身份認證平臺</title>"},
+        {"undeclared gbk", gbk, "text/html", page},
+        {"content type wins over meta", []byte(`<meta charset="gbk"><title>统一身份认证平台</title>`), "text/html; charset=utf-8", `<meta charset="gbk"><title>统一身份认证平台</title>`},
+        {"undeclared utf-8", []byte(page), "text/html", page},
+        {"json", encodeText(t, simplifiedchinese.GBK, `{"msg": "登录成功"}`), "application/json; charset=gbk", `{"msg": "登录成功"}`},
+        {"binary", gbk, "image/png", string(gbk)},
+        {"empty", nil, "text/html; charset=gbk", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := string(DecodeToUTF8(tt.body, tt.contentType)); got != tt.want {
+                t.Errorf("decoded %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -2,12 +2,19 @@ package utils
 
 import (
     "bytes"
+    "context"
+    "crypto/sha256"
     "crypto/tls"
+    "crypto/x509/pkix"
     "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
     "fmt"
+    "math/big"
     "math/rand"
     "net"
     "net/http"
+    "net/http/httptrace"
     "net/url"
     "regexp"
     "strings"
@@ -106,6 +113,7 @@ func createHybridTransport(proxy string) *http.Transport {
     // 创建混合传输层
     transport := &http.Transport{
         DialTLS: func(network, addr string) (net.Conn, error) {
+            // 返回 *tls.Conn 时由 http.Transport 将握手状态填入 resp.TLS
             conn, err := tls.Dial(network, addr, stdTLSConfig)
             if err == nil {
                 return conn, nil
@@ -162,6 +170,100 @@ func connectWithGMTLS(network, addr string) (net.Conn, error) {
     return conn, nil
 }
 
+// CertInfo 为目标 TLS 证书的摘要信息
+type CertInfo struct {
+    Subject string
+    Issuer  string
+    SANs    []string
+    Serial  string
+    SHA256  string
+    GMTLS   bool // 是否通过国密（SM2）TLS 建立连接
+}
+
+// Text 返回用于 location 为 cert 的规则匹配的证书文本，每行一个字段
+func (c *CertInfo) Text() string {
+    if c == nil {
+        return ""
+    }
+    return fmt.Sprintf("Subject: %s\nIssuer: %s\nSAN: %s\nSerial: %s\nSHA256: %s",
+        c.Subject, c.Issuer, strings.Join(c.SANs, ", "), c.Serial, c.SHA256)
+}
+
+// usedConnKey 为请求 context 中记录实际使用的连接的键
+type usedConnKey struct{}
+
+// usedConn 记录请求（包括重定向后的请求）最后使用的连接
+// 自定义 DialTLS 建立的国密连接不会出现在 resp.TLS 中，需要从连接本身取得对端证书
+type usedConn struct {
+    mu   sync.Mutex
+    conn net.Conn
+}
+
+// do 发送请求，并在请求的 context 中记录实际使用的连接，连接随响应一起释放
+func do(req *http.Request) (*http.Response, error) {
+    used := &usedConn{}
+    trace := &httptrace.ClientTrace{
+        GotConn: func(info httptrace.GotConnInfo) {
+            used.mu.Lock()
+            used.conn = info.Conn
+            used.mu.Unlock()
+        },
+    }
+    ctx := context.WithValue(httptrace.WithClientTrace(req.Context(), trace), usedConnKey{}, used)
+    return httpClient.Do(req.WithContext(ctx))
+}
+
+func newCertInfo(raw []byte, subject, issuer pkix.Name, dnsNames []string, ips []net.IP, emails []string, serial *big.Int) *CertInfo {
+    sum := sha256.Sum256(raw)
+    info := &CertInfo{
+        Subject: subject.String(),
+        Issuer:  issuer.String(),
+        SHA256:  hex.EncodeToString(sum[:]),
+    }
+    info.SANs = append(info.SANs, dnsNames...)
+    for _, ip := range ips {
+        info.SANs = append(info.SANs, ip.String())
+    }
+    info.SANs = append(info.SANs, emails...)
+    if serial != nil {
+        info.Serial = strings.ToUpper(serial.Text(16))
+    }
+    return info
+}
+
+// PeerCertificate 返回响应所在连接的对端证书，非 HTTPS 或未能获取时返回 nil
+func PeerCertificate(resp *http.Response) *CertInfo {
+    if resp == nil {
+        return nil
+    }
+    if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+        cert := resp.TLS.PeerCertificates[0]
+        return newCertInfo(cert.Raw, cert.Subject, cert.Issuer, cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.SerialNumber)
+    }
+    if resp.Request == nil {
+        return nil
+    }
+    used, _ := resp.Request.Context().Value(usedConnKey{}).(*usedConn)
+    if used == nil {
+        return nil
+    }
+    used.mu.Lock()
+    conn := used.conn
+    used.mu.Unlock()
+    gmConn, ok := conn.(*gmtls.Conn)
+    if !ok {
+        return nil
+    }
+    state := gmConn.ConnectionState()
+    if len(state.PeerCertificates) == 0 {
+        return nil
+    }
+    cert := state.PeerCertificates[0]
+    info := newCertInfo(cert.Raw, cert.Subject, cert.Issuer, cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.SerialNumber)
+    info.GMTLS = true
+    return info
+}
+
 func setRequestHeaders(req *http.Request, headers map[string]string) {
     req.Header.Set("User-Agent", RandomUserAgent())
     req.Header.Set("Accept", "*/*;q=0.8")
@@ -190,7 +292,7 @@ func Head(url string, headers map[string]string) (*http.Response, error) {
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func Get(url string, headers map[string]string) (*http.Response, error) {
@@ -204,7 +306,25 @@ func Get(url string, headers map[string]string) (*http.Response, error) {
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
+}
+
+// Request 使用指定的方法发送不带请求体的请求，用于指纹声明的主动探测
+func Request(method string, url string, headers map[string]string) (*http.Response, error) {
+    if httpClient == nil {
+        return nil, fmt.Errorf("HTTP client not initialized.")
+    }
+
+    if method == "" {
+        method = "GET"
+    }
+    req, err := http.NewRequest(method, url, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    setRequestHeaders(req, headers)
+    return do(req)
 }
 
 func Options(url string, headers map[string]string) (*http.Response, error) {
@@ -218,7 +338,7 @@ func Options(url string, headers map[string]string) (*http.Response, error) {
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func Trace(url string, headers map[string]string) (*http.Response, error) {
@@ -232,7 +352,7 @@ func Trace(url string, headers map[string]string) (*http.Response, error) {
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func Post(url string, data []byte, headers map[string]string) (*http.Response, error) {
@@ -242,7 +362,7 @@ func Post(url string, data []byte, headers map[string]string) (*http.Response, e
     }
     
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func Put(url string, data []byte, headers map[string]string) (*http.Response, error) {
@@ -252,7 +372,7 @@ func Put(url string, data []byte, headers map[string]string) (*http.Response, er
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func Delete(url string, data []byte, headers map[string]string) (*http.Response, error) {
@@ -262,7 +382,7 @@ func Delete(url string, data []byte, headers map[string]string) (*http.Response,
     }
 
     setRequestHeaders(req, headers)
-    return httpClient.Do(req)
+    return do(req)
 }
 
 func FetchTitle(body []byte) string {
@@ -275,25 +395,89 @@ func FetchTitle(body []byte) string {
     return title
 }
 
-func FetchFavicon(body []byte) string {
-    doc, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(body)))
+// iconRels 为声明页面图标的 link rel 取值
+var iconRels = map[string]bool{
+    "icon":                         true,
+    "apple-touch-icon":             true,
+    "apple-touch-icon-precomposed": true,
+    "mask-icon":                    true,
+}
+
+// FetchIconCandidates 按文档顺序提取页面声明的全部图标地址（包含 data: URI）以及 manifest 地址
+// "shortcut icon" 等组合写法按 rel 中的任一取值判断
+func FetchIconCandidates(body []byte) ([]string, []string) {
+    doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
     if err != nil {
-        return ""
+        return nil, nil
     }
 
-    var faviconURL string
-    doc.Find("link[rel='icon'], link[rel='shortcut icon']").Each(func(i int, s *goquery.Selection) {
-        href, exists := s.Attr("href")
-        if exists {
-            faviconURL = href
+    var icons, manifests []string
+    doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+        rel, _ := s.Attr("rel")
+        href, _ := s.Attr("href")
+        href = strings.TrimSpace(href)
+        if href == "" {
             return
         }
+        for _, value := range strings.Fields(strings.ToLower(rel)) {
+            if iconRels[value] {
+                icons = append(icons, href)
+                return
+            }
+            if value == "manifest" {
+                manifests = append(manifests, href)
+                return
+            }
+        }
     })
-    if faviconURL == "" {
-        faviconURL = "/favicon.ico"
+    return icons, manifests
+}
+
+// ParseManifestIcons 提取 Web App Manifest 中 icons 声明的图标地址
+func ParseManifestIcons(data []byte) []string {
+    var manifest struct {
+        Icons []struct {
+            Src string `json:"src"`
+        } `json:"icons"`
     }
-    
-    return faviconURL
+    if err := json.Unmarshal(data, &manifest); err != nil {
+        return nil
+    }
+    var icons []string
+    for _, icon := range manifest.Icons {
+        if src := strings.TrimSpace(icon.Src); src != "" {
+            icons = append(icons, src)
+        }
+    }
+    return icons
+}
+
+// FetchAssetLinks 提取页面中 <script src> 引用的脚本和 <link rel=stylesheet> 引用的样式表地址
+func FetchAssetLinks(body []byte) ([]string, []string) {
+    doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+    if err != nil {
+        return nil, nil
+    }
+
+    var scripts, styles []string
+    doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+        if src, exists := s.Attr("src"); exists && strings.TrimSpace(src) != "" {
+            scripts = append(scripts, strings.TrimSpace(src))
+        }
+    })
+    doc.Find("link[href]").Each(func(i int, s *goquery.Selection) {
+        rel, _ := s.Attr("rel")
+        for _, r := range strings.Fields(strings.ToLower(rel)) {
+            if r == "stylesheet" {
+                href, _ := s.Attr("href")
+                if strings.TrimSpace(href) != "" {
+                    styles = append(styles, strings.TrimSpace(href))
+                }
+                break
+            }
+        }
+    })
+    return scripts, styles
 }
 
 func GetBaseURL(fullURL string) (string, error) {
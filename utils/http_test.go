@@ -0,0 +1,49 @@
+package utils
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestPeerCertificate(t *testing.T) {
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/" {
+            http.Redirect(w, r, "/login", http.StatusFound)
+            return
+        }
+        w.Write([]byte("ok"))
+    })
+    tlsServer := httptest.NewTLSServer(handler)
+    defer tlsServer.Close()
+    plainServer := httptest.NewServer(handler)
+    defer plainServer.Close()
+
+    if err := InitializeHTTPClient("", 5*time.Second, 3); err != nil {
+        t.Fatal(err)
+    }
+    for _, tt := range []struct {
+        url     string
+        wantTLS bool
+    }{
+        {tlsServer.URL, true},
+        {tlsServer.URL + "/login", true},
+        {plainServer.URL, false},
+    } {
+        resp, err := Get(tt.url, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        info := PeerCertificate(resp)
+        if (info != nil) != tt.wantTLS {
+            t.Errorf("%s: certificate = %+v, want present %v", tt.url, info, tt.wantTLS)
+            continue
+        }
+        if info != nil && (!strings.Contains(info.Issuer, "Acme Co") || info.SHA256 == "") {
+            t.Errorf("%s: unexpected certificate %+v", tt.url, info)
+        }
+    }
+}